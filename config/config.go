@@ -0,0 +1,345 @@
+// Package config loads the settings of a command from, in increasing priority, the
+// defaults already in a struct, a YAML or TOML file, environment variables and flags.
+//
+// The fields of the struct are found with tags:
+//
+//	Host     string `config:"host" env:"DB_HOST" usage:"the postgres host"`
+//	Password string `config:"password" env:"DB_PASSWORD" secret:"true"`
+//
+// The config tag is the key in the file and, joined with the keys of any enclosing
+// structs by a dot, the flag name, e.g. -db.host. A field without a config tag is
+// ignored, unless it is an embedded struct whose fields are then promoted. Every env
+// variable also has a _FILE variant, e.g. DB_PASSWORD_FILE, naming a file that holds the
+// value, for secrets mounted by docker or kubernetes. A secret is redacted by Print.
+//
+// Fields may be a string, bool, int, float64, time.Duration or a []string, which is comma
+// separated in env variables and flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the env variable naming the config file when there is no -config flag.
+const FileEnv = "CONFIG_FILE"
+
+// Redacted replaces the value of a secret in Print.
+const Redacted = "<redacted>"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a settable field of a config struct.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// Load fills cfg, a pointer to a struct, from the file named by the -config flag or
+// CONFIG_FILE, the environment and the flags in args, returning the arguments left after
+// the flags, e.g. a subcommand. Every invalid value is reported, naming where it came
+// from, and cfg is then checked by its Validate method if it has one. It returns
+// flag.ErrHelp for -h.
+func Load(cfg any, name string, args []string) ([]string, error) {
+	fields, err := fieldsOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := flags.String("config", os.Getenv(FileEnv), "a YAML or TOML config file, also "+FileEnv)
+	var set []flagValue
+	for _, f := range fields {
+		flags.Func(f.key, usage(f), func(s string) error {
+			set = append(set, flagValue{f, s})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// apply the layers in order, so each overrides those before it
+	var errs []error
+	if *file != "" {
+		errs = append(errs, loadFile(fields, *file)...)
+	}
+	errs = append(errs, loadEnv(fields)...)
+	for _, v := range set {
+		errs = append(errs, v.f.set(v.s, "flag -"+v.f.key))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if v, ok := cfg.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	return flags.Args(), nil
+}
+
+// Print writes the effective config as sorted key = value lines, with secrets redacted.
+func Print(w io.Writer, cfg any) error {
+	fields, err := fieldsOf(cfg)
+	if err != nil {
+		return err
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	for _, f := range fields {
+		value := f.String()
+		if f.secret && value != "" {
+			value = Redacted
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s\n", f.key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type flagValue struct {
+	f field
+	s string
+}
+
+// usage returns the flag usage of f, naming its env variable.
+func usage(f field) string {
+	switch {
+	case f.env == "":
+		return f.usage
+	case f.usage == "":
+		return "also " + f.env
+	default:
+		return f.usage + ", also " + f.env
+	}
+}
+
+// fieldsOf returns the tagged fields of cfg, which must be a pointer to a struct.
+func fieldsOf(cfg any) ([]field, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config %T is not a pointer to a struct", cfg)
+	}
+	return appendFields(nil, "", v.Elem())
+}
+
+func appendFields(fields []field, prefix string, v reflect.Value) ([]field, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("config")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				var err error
+				if fields, err = appendFields(fields, prefix, v.Field(i)); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			var err error
+			if fields, err = appendFields(fields, key, v.Field(i)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		switch sf.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		case reflect.Slice:
+			if sf.Type.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("config key %s has unsupported type %s", key, sf.Type)
+			}
+		default:
+			return nil, fmt.Errorf("config key %s has unsupported type %s", key, sf.Type)
+		}
+		fields = append(fields, field{
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return fields, nil
+}
+
+// loadFile sets fields from a YAML or TOML file, chosen by its extension. Unknown keys are
+// errors so that a typo is not silently ignored.
+func loadFile(fields []field, path string) []error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	doc := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return []error{fmt.Errorf("config file %s: unsupported extension %q, want .yaml, .yml or .toml", path, ext)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	values := map[string]string{}
+	if err := flatten(values, "", doc); err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if s, ok := values[f.key]; ok {
+			errs = append(errs, f.set(s, "config file "+path+" key "+f.key))
+			delete(values, f.key)
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("config file %s: unknown key %s", path, key))
+	}
+	return errs
+}
+
+// flatten adds the values in doc to values, keyed by their dotted path.
+func flatten(values map[string]string, prefix string, doc map[string]any) error {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(values, key, v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				if _, ok := item.(map[string]any); ok {
+					return fmt.Errorf("key %s must be a list of values", key)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// loadEnv sets fields from their env variables, or the file named by the _FILE variant.
+func loadEnv(fields []field) []error {
+	var errs []error
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		s, ok := os.LookupEnv(f.env)
+		path, fromFile := os.LookupEnv(f.env + "_FILE")
+		switch {
+		case ok && fromFile:
+			errs = append(errs, fmt.Errorf("env %s and %s_FILE are both set, use one", f.env, f.env))
+		case fromFile:
+			b, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("env %s_FILE: %w", f.env, err))
+				continue
+			}
+			// files usually end with a new line that is not part of the value
+			errs = append(errs, f.set(strings.TrimRight(string(b), "\r\n"), "env "+f.env+"_FILE"))
+		case ok:
+			errs = append(errs, f.set(s, "env "+f.env))
+		}
+	}
+	return errs
+}
+
+// set parses s into f, naming source in any error.
+func (f field) set(s, source string) error {
+	invalid := func(want string) error {
+		// never echo a secret back in an error
+		if f.secret {
+			return fmt.Errorf("%s: not a valid %s", source, want)
+		}
+		return fmt.Errorf("%s: %q is not a valid %s", source, s, want)
+	}
+
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return invalid("duration such as 30s or 1m")
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalid("bool")
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return invalid("integer")
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return invalid("number")
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// String formats the value of f as it would be given in an env variable or flag.
+func (f field) String() string {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,234 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/config"
+)
+
+type db struct {
+	Host     string `config:"host" env:"TEST_DB_HOST"`
+	Port     int    `config:"port" env:"TEST_DB_PORT"`
+	Password string `config:"password" env:"TEST_DB_PASSWORD" secret:"true"`
+}
+
+type testConfig struct {
+	DB       db            `config:"db"`
+	Timeout  time.Duration `config:"timeout" env:"TEST_TIMEOUT"`
+	Verbose  bool          `config:"verbose"`
+	Ratio    float64       `config:"ratio"`
+	Replicas []string      `config:"replicas" env:"TEST_REPLICAS"`
+}
+
+func (c testConfig) Validate() error {
+	if c.DB.Port == 0 {
+		return errors.New("db.port is required")
+	}
+	return nil
+}
+
+func defaults() testConfig {
+	return testConfig{DB: db{Host: "localhost", Port: 5432}, Timeout: time.Second}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := defaults()
+		args, err := config.Load(&cfg, "test", []string{"serve", "-addr", ":8080"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DB.Host != "localhost" || cfg.DB.Port != 5432 || cfg.Timeout != time.Second {
+			t.Errorf("got: %+v, but want the defaults", cfg)
+		}
+		if strings.Join(args, " ") != "serve -addr :8080" {
+			t.Errorf("got: %v, but want the subcommand args", args)
+		}
+	})
+
+	for _, ext := range []string{"yaml", "toml"} {
+		t.Run(ext+" file", func(t *testing.T) {
+			content := "timeout: 5s\nreplicas: [a, b]\nverbose: true\nratio: 0.5\ndb:\n  host: db.example.com\n  password: secret\n"
+			if ext == "toml" {
+				content = "timeout = \"5s\"\nreplicas = [\"a\", \"b\"]\nverbose = true\nratio = 0.5\n[db]\nhost = \"db.example.com\"\npassword = \"secret\"\n"
+			}
+			path := writeFile(t, "config."+ext, content)
+
+			cfg := defaults()
+			if _, err := config.Load(&cfg, "test", []string{"-config", path}); err != nil {
+				t.Fatal(err)
+			}
+			want := testConfig{DB: db{Host: "db.example.com", Port: 5432, Password: "secret"},
+				Timeout: 5 * time.Second, Verbose: true, Ratio: 0.5, Replicas: []string{"a", "b"}}
+			if cfg.DB != want.DB || cfg.Timeout != want.Timeout || !cfg.Verbose || cfg.Ratio != want.Ratio ||
+				strings.Join(cfg.Replicas, ",") != "a,b" {
+				t.Errorf("got: %+v, but want %+v", cfg, want)
+			}
+		})
+	}
+
+	t.Run("precedence", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "db:\n  host: file\n  port: 1\ntimeout: 2s\n")
+		t.Setenv(config.FileEnv, path)
+		t.Setenv("TEST_DB_PORT", "2")
+		t.Setenv("TEST_TIMEOUT", "3s")
+
+		cfg := defaults()
+		if _, err := config.Load(&cfg, "test", []string{"-timeout", "4s"}); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DB.Host != "file" || cfg.DB.Port != 2 || cfg.Timeout != 4*time.Second {
+			t.Errorf("got: %+v, but want host from the file, port from env and timeout from a flag", cfg)
+		}
+	})
+
+	t.Run("secret file", func(t *testing.T) {
+		t.Setenv("TEST_DB_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+		cfg := defaults()
+		if _, err := config.Load(&cfg, "test", nil); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DB.Password != "s3cret" {
+			t.Errorf("got: %q, but want %q", cfg.DB.Password, "s3cret")
+		}
+	})
+
+	t.Run("list env", func(t *testing.T) {
+		t.Setenv("TEST_REPLICAS", "a, b,,c")
+		cfg := defaults()
+		if _, err := config.Load(&cfg, "test", nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(cfg.Replicas, "|"); got != "a|b|c" {
+			t.Errorf("got: %s, but want a|b|c", got)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		cfg := defaults()
+		if _, err := config.Load(&cfg, "test", []string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("got: %v, but want %v", err, flag.ErrHelp)
+		}
+	})
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string
+		args []string
+		want string
+	}{
+		{name: "env", env: map[string]string{"TEST_DB_PORT": "abc"}, want: `env TEST_DB_PORT: "abc" is not a valid integer`},
+		{name: "flag", args: []string{"-timeout", "5"}, want: `flag -timeout: "5" is not a valid duration`},
+		{name: "file", file: "db:\n  port: lots\n", want: `key db.port: "lots" is not a valid integer`},
+		{name: "unknown key", file: "db:\n  hots: x\n", want: "unknown key db.hots"},
+		{name: "both env and file", env: map[string]string{"TEST_DB_PASSWORD": "a", "TEST_DB_PASSWORD_FILE": "b"},
+			want: "TEST_DB_PASSWORD and TEST_DB_PASSWORD_FILE are both set"},
+		{name: "missing secret file", env: map[string]string{"TEST_DB_PASSWORD_FILE": "/no/such/file"}, want: "env TEST_DB_PASSWORD_FILE"},
+		{name: "validate", args: []string{"-db.port", "0"}, want: "invalid config: db.port is required"},
+		{name: "unknown flag", args: []string{"-nope"}, want: "flag provided but not defined: -nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, "config.yaml", tt.file)}, args...)
+			}
+
+			cfg := defaults()
+			_, err := config.Load(&cfg, "test", args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got: %v, but want %s", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("all errors", func(t *testing.T) {
+		t.Setenv("TEST_DB_PORT", "abc")
+		t.Setenv("TEST_TIMEOUT", "abc")
+		cfg := defaults()
+		_, err := config.Load(&cfg, "test", nil)
+		if err == nil || !strings.Contains(err.Error(), "TEST_DB_PORT") || !strings.Contains(err.Error(), "TEST_TIMEOUT") {
+			t.Errorf("got: %v, but want both errors", err)
+		}
+	})
+
+	t.Run("secret not echoed", func(t *testing.T) {
+		type secretInt struct {
+			PIN int `config:"pin" env:"TEST_PIN" secret:"true"`
+		}
+		t.Setenv("TEST_PIN", "hunter2")
+		_, err := config.Load(&secretInt{}, "test", nil)
+		if err == nil || strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("got: %v, but want an error without the secret", err)
+		}
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		cfg := defaults()
+		_, err := config.Load(&cfg, "test", []string{"-config", writeFile(t, "config.json", "{}")})
+		if err == nil || !strings.Contains(err.Error(), "unsupported extension") {
+			t.Errorf("got: %v, but want an unsupported extension error", err)
+		}
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		if _, err := config.Load(defaults(), "test", nil); err == nil {
+			t.Error("got no error for a struct value")
+		}
+	})
+}
+
+func TestPrint(t *testing.T) {
+	cfg := defaults()
+	cfg.DB.Password = "secret"
+	cfg.Replicas = []string{"a", "b"}
+
+	var buf bytes.Buffer
+	if err := config.Print(&buf, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := `db.host = localhost
+db.password = <redacted>
+db.port = 5432
+ratio = 0
+replicas = a,b
+timeout = 1s
+verbose = false
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got: %s, but want %s", got, want)
+	}
+
+	t.Run("empty secret", func(t *testing.T) {
+		cfg := defaults()
+		var buf bytes.Buffer
+		if err := config.Print(&buf, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "db.password = \n") {
+			t.Errorf("got: %s, but want an empty password shown as unset", buf.String())
+		}
+	})
+}
@@ -0,0 +1,21 @@
+module github.com/ayubmalik/go-cookbook/grpc
+
+go 1.23
+
+require (
+	github.com/ayubmalik/go-cookbook/pharmacy v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.67.1
+	google.golang.org/protobuf v1.36.9
+)
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.3 // indirect
+	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
+)
+
+replace github.com/ayubmalik/go-cookbook/pharmacy => ../pharmacy
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
@@ -0,0 +1,177 @@
+// Command grpc serves pharmacies over gRPC and is also a client for the same service.
+//
+//	go run . serve [-addr :9090] [-csv ../sql/data/sample-pharmacies.csv] [-jwt-key public.pem]
+//	go run . get [-addr localhost:9090] FA512
+//	go run . search [-addr localhost:9090] LE
+//	go run . create [-addr localhost:9090] [-token jwt] -code NEW1 -name "A test pharmacy" -postcode "LE1 1AA"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/ayubmalik/go-cookbook/grpc/pharmacypb"
+	"github.com/ayubmalik/go-cookbook/grpc/server"
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+	"github.com/ayubmalik/go-cookbook/pharmacy/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalln("usage: serve|get|search|create [flags]")
+	}
+
+	// stop the server or cancel the call on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// run executes the command named by args[0].
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	switch args[0] {
+	case "serve":
+		return runServe(ctx, args[1:], stdout)
+	case "get", "search", "create":
+		return runClient(ctx, args[0], args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runServe serves an in memory repo loaded from a pharmacy CSV until ctx is cancelled.
+func runServe(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		flags = flag.NewFlagSet("serve", flag.ExitOnError)
+		addr  = flags.String("addr", ":9090", "the address to listen on")
+		csv   = flags.String("csv", "../sql/data/sample-pharmacies.csv", "the pharmacies to serve")
+	)
+	verifier := auth.VerifierFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	v, err := verifier()
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		opts = append(opts, grpc.UnaryInterceptor(server.AuthInterceptor(v)))
+	} else {
+		_, _ = fmt.Fprintln(stdout, "warning: no -jwt-key so creates are not authenticated")
+	}
+
+	repo, err := memory.New()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*csv)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stats, err := repo.Import(ctx, f)
+	if err != nil {
+		return err
+	}
+	stats.Print(stdout)
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer(opts...)
+	pharmacypb.RegisterPharmacyServiceServer(srv, server.New(repo))
+
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+
+	_, _ = fmt.Fprintf(stdout, "listening on %s\n", lis.Addr())
+	return srv.Serve(lis)
+}
+
+// runClient calls the service running at -addr.
+func runClient(ctx context.Context, cmd string, args []string, stdout io.Writer) error {
+	var (
+		flags    = flag.NewFlagSet(cmd, flag.ExitOnError)
+		addr     = flags.String("addr", "localhost:9090", "the address of the server")
+		code     = flags.String("code", "", "create: the pharmacy code")
+		name     = flags.String("name", "", "create: the pharmacy name")
+		postcode = flags.String("postcode", "", "create: the pharmacy postcode")
+		token    = flags.String("token", os.Getenv("PHARMACY_TOKEN"), "create: the bearer token, see the sql examples token command")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := pharmacypb.NewPharmacyServiceClient(conn)
+
+	switch cmd {
+	case "get":
+		if flags.NArg() != 1 {
+			return errors.New("usage: get [-addr host:port] code")
+		}
+		p, err := client.GetByCode(ctx, &pharmacypb.GetByCodeRequest{Code: flags.Arg(0)})
+		if err != nil {
+			return err
+		}
+		printPharmacy(stdout, p)
+
+	case "search":
+		if flags.NArg() != 1 {
+			return errors.New("usage: search [-addr host:port] postcode")
+		}
+		stream, err := client.SearchByPostcode(ctx, &pharmacypb.SearchByPostcodeRequest{Postcode: flags.Arg(0)})
+		if err != nil {
+			return err
+		}
+		for {
+			p, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			printPharmacy(stdout, p)
+		}
+
+	case "create":
+		if *token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+		}
+		p := server.ToProto(pharmacy.Pharmacy{Code: *code, Name: *name, Postcode: *postcode})
+		p, err := client.Create(ctx, &pharmacypb.CreateRequest{Pharmacy: p})
+		if err != nil {
+			return err
+		}
+		printPharmacy(stdout, p)
+	}
+	return nil
+}
+
+func printPharmacy(w io.Writer, p *pharmacypb.Pharmacy) {
+	_, _ = fmt.Fprintln(w, p.GetCode(), p.GetName(), p.GetPostcode())
+}
@@ -0,0 +1,6 @@
+// Package pharmacypb is the generated protobuf and gRPC code for
+// proto/pharmacy/v1/pharmacy.proto. Regenerate it with protoc, protoc-gen-go and
+// protoc-gen-go-grpc on the PATH by running go generate.
+package pharmacypb
+
+//go:generate protoc -I ../proto --go_out=.. --go_opt=module=github.com/ayubmalik/go-cookbook/grpc --go-grpc_out=.. --go-grpc_opt=module=github.com/ayubmalik/go-cookbook/grpc pharmacy/v1/pharmacy.proto
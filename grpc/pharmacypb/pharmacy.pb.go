@@ -0,0 +1,410 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: pharmacy/v1/pharmacy.proto
+
+package pharmacypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Pharmacy mirrors the pharmacy table. Unset optional fields are NULL.
+type Pharmacy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	AddrLine_1    *string                `protobuf:"bytes,3,opt,name=addr_line_1,json=addrLine1,proto3,oneof" json:"addr_line_1,omitempty"`
+	AddrLine_2    *string                `protobuf:"bytes,4,opt,name=addr_line_2,json=addrLine2,proto3,oneof" json:"addr_line_2,omitempty"`
+	AddrLine_3    *string                `protobuf:"bytes,5,opt,name=addr_line_3,json=addrLine3,proto3,oneof" json:"addr_line_3,omitempty"`
+	AddrLine_4    *string                `protobuf:"bytes,6,opt,name=addr_line_4,json=addrLine4,proto3,oneof" json:"addr_line_4,omitempty"`
+	Postcode      string                 `protobuf:"bytes,7,opt,name=postcode,proto3" json:"postcode,omitempty"`
+	Phone         *string                `protobuf:"bytes,8,opt,name=phone,proto3,oneof" json:"phone,omitempty"`
+	LatLng        *LatLng                `protobuf:"bytes,9,opt,name=lat_lng,json=latLng,proto3" json:"lat_lng,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pharmacy) Reset() {
+	*x = Pharmacy{}
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pharmacy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pharmacy) ProtoMessage() {}
+
+func (x *Pharmacy) ProtoReflect() protoreflect.Message {
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pharmacy.ProtoReflect.Descriptor instead.
+func (*Pharmacy) Descriptor() ([]byte, []int) {
+	return file_pharmacy_v1_pharmacy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Pharmacy) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAddrLine_1() string {
+	if x != nil && x.AddrLine_1 != nil {
+		return *x.AddrLine_1
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAddrLine_2() string {
+	if x != nil && x.AddrLine_2 != nil {
+		return *x.AddrLine_2
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAddrLine_3() string {
+	if x != nil && x.AddrLine_3 != nil {
+		return *x.AddrLine_3
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetAddrLine_4() string {
+	if x != nil && x.AddrLine_4 != nil {
+		return *x.AddrLine_4
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetPostcode() string {
+	if x != nil {
+		return x.Postcode
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetPhone() string {
+	if x != nil && x.Phone != nil {
+		return *x.Phone
+	}
+	return ""
+}
+
+func (x *Pharmacy) GetLatLng() *LatLng {
+	if x != nil {
+		return x.LatLng
+	}
+	return nil
+}
+
+type LatLng struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lat           float32                `protobuf:"fixed32,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng           float32                `protobuf:"fixed32,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LatLng) Reset() {
+	*x = LatLng{}
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LatLng) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LatLng) ProtoMessage() {}
+
+func (x *LatLng) ProtoReflect() protoreflect.Message {
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LatLng.ProtoReflect.Descriptor instead.
+func (*LatLng) Descriptor() ([]byte, []int) {
+	return file_pharmacy_v1_pharmacy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LatLng) GetLat() float32 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *LatLng) GetLng() float32 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+type GetByCodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByCodeRequest) Reset() {
+	*x = GetByCodeRequest{}
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByCodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByCodeRequest) ProtoMessage() {}
+
+func (x *GetByCodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByCodeRequest.ProtoReflect.Descriptor instead.
+func (*GetByCodeRequest) Descriptor() ([]byte, []int) {
+	return file_pharmacy_v1_pharmacy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetByCodeRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+type SearchByPostcodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Postcode      string                 `protobuf:"bytes,1,opt,name=postcode,proto3" json:"postcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchByPostcodeRequest) Reset() {
+	*x = SearchByPostcodeRequest{}
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchByPostcodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchByPostcodeRequest) ProtoMessage() {}
+
+func (x *SearchByPostcodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchByPostcodeRequest.ProtoReflect.Descriptor instead.
+func (*SearchByPostcodeRequest) Descriptor() ([]byte, []int) {
+	return file_pharmacy_v1_pharmacy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchByPostcodeRequest) GetPostcode() string {
+	if x != nil {
+		return x.Postcode
+	}
+	return ""
+}
+
+type CreateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pharmacy      *Pharmacy              `protobuf:"bytes,1,opt,name=pharmacy,proto3" json:"pharmacy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRequest) Reset() {
+	*x = CreateRequest{}
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRequest) ProtoMessage() {}
+
+func (x *CreateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pharmacy_v1_pharmacy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRequest.ProtoReflect.Descriptor instead.
+func (*CreateRequest) Descriptor() ([]byte, []int) {
+	return file_pharmacy_v1_pharmacy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateRequest) GetPharmacy() *Pharmacy {
+	if x != nil {
+		return x.Pharmacy
+	}
+	return nil
+}
+
+var File_pharmacy_v1_pharmacy_proto protoreflect.FileDescriptor
+
+const file_pharmacy_v1_pharmacy_proto_rawDesc = "" +
+	"\n" +
+	"\x1apharmacy/v1/pharmacy.proto\x12\vpharmacy.v1\"\xf5\x02\n" +
+	"\bPharmacy\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12#\n" +
+	"\vaddr_line_1\x18\x03 \x01(\tH\x00R\taddrLine1\x88\x01\x01\x12#\n" +
+	"\vaddr_line_2\x18\x04 \x01(\tH\x01R\taddrLine2\x88\x01\x01\x12#\n" +
+	"\vaddr_line_3\x18\x05 \x01(\tH\x02R\taddrLine3\x88\x01\x01\x12#\n" +
+	"\vaddr_line_4\x18\x06 \x01(\tH\x03R\taddrLine4\x88\x01\x01\x12\x1a\n" +
+	"\bpostcode\x18\a \x01(\tR\bpostcode\x12\x19\n" +
+	"\x05phone\x18\b \x01(\tH\x04R\x05phone\x88\x01\x01\x12,\n" +
+	"\alat_lng\x18\t \x01(\v2\x13.pharmacy.v1.LatLngR\x06latLngB\x0e\n" +
+	"\f_addr_line_1B\x0e\n" +
+	"\f_addr_line_2B\x0e\n" +
+	"\f_addr_line_3B\x0e\n" +
+	"\f_addr_line_4B\b\n" +
+	"\x06_phone\",\n" +
+	"\x06LatLng\x12\x10\n" +
+	"\x03lat\x18\x01 \x01(\x02R\x03lat\x12\x10\n" +
+	"\x03lng\x18\x02 \x01(\x02R\x03lng\"&\n" +
+	"\x10GetByCodeRequest\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\"5\n" +
+	"\x17SearchByPostcodeRequest\x12\x1a\n" +
+	"\bpostcode\x18\x01 \x01(\tR\bpostcode\"B\n" +
+	"\rCreateRequest\x121\n" +
+	"\bpharmacy\x18\x01 \x01(\v2\x15.pharmacy.v1.PharmacyR\bpharmacy2\xe4\x01\n" +
+	"\x0fPharmacyService\x12A\n" +
+	"\tGetByCode\x12\x1d.pharmacy.v1.GetByCodeRequest\x1a\x15.pharmacy.v1.Pharmacy\x12Q\n" +
+	"\x10SearchByPostcode\x12$.pharmacy.v1.SearchByPostcodeRequest\x1a\x15.pharmacy.v1.Pharmacy0\x01\x12;\n" +
+	"\x06Create\x12\x1a.pharmacy.v1.CreateRequest\x1a\x15.pharmacy.v1.PharmacyB2Z0github.com/ayubmalik/go-cookbook/grpc/pharmacypbb\x06proto3"
+
+var (
+	file_pharmacy_v1_pharmacy_proto_rawDescOnce sync.Once
+	file_pharmacy_v1_pharmacy_proto_rawDescData []byte
+)
+
+func file_pharmacy_v1_pharmacy_proto_rawDescGZIP() []byte {
+	file_pharmacy_v1_pharmacy_proto_rawDescOnce.Do(func() {
+		file_pharmacy_v1_pharmacy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pharmacy_v1_pharmacy_proto_rawDesc), len(file_pharmacy_v1_pharmacy_proto_rawDesc)))
+	})
+	return file_pharmacy_v1_pharmacy_proto_rawDescData
+}
+
+var file_pharmacy_v1_pharmacy_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pharmacy_v1_pharmacy_proto_goTypes = []any{
+	(*Pharmacy)(nil),                // 0: pharmacy.v1.Pharmacy
+	(*LatLng)(nil),                  // 1: pharmacy.v1.LatLng
+	(*GetByCodeRequest)(nil),        // 2: pharmacy.v1.GetByCodeRequest
+	(*SearchByPostcodeRequest)(nil), // 3: pharmacy.v1.SearchByPostcodeRequest
+	(*CreateRequest)(nil),           // 4: pharmacy.v1.CreateRequest
+}
+var file_pharmacy_v1_pharmacy_proto_depIdxs = []int32{
+	1, // 0: pharmacy.v1.Pharmacy.lat_lng:type_name -> pharmacy.v1.LatLng
+	0, // 1: pharmacy.v1.CreateRequest.pharmacy:type_name -> pharmacy.v1.Pharmacy
+	2, // 2: pharmacy.v1.PharmacyService.GetByCode:input_type -> pharmacy.v1.GetByCodeRequest
+	3, // 3: pharmacy.v1.PharmacyService.SearchByPostcode:input_type -> pharmacy.v1.SearchByPostcodeRequest
+	4, // 4: pharmacy.v1.PharmacyService.Create:input_type -> pharmacy.v1.CreateRequest
+	0, // 5: pharmacy.v1.PharmacyService.GetByCode:output_type -> pharmacy.v1.Pharmacy
+	0, // 6: pharmacy.v1.PharmacyService.SearchByPostcode:output_type -> pharmacy.v1.Pharmacy
+	0, // 7: pharmacy.v1.PharmacyService.Create:output_type -> pharmacy.v1.Pharmacy
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_pharmacy_v1_pharmacy_proto_init() }
+func file_pharmacy_v1_pharmacy_proto_init() {
+	if File_pharmacy_v1_pharmacy_proto != nil {
+		return
+	}
+	file_pharmacy_v1_pharmacy_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pharmacy_v1_pharmacy_proto_rawDesc), len(file_pharmacy_v1_pharmacy_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pharmacy_v1_pharmacy_proto_goTypes,
+		DependencyIndexes: file_pharmacy_v1_pharmacy_proto_depIdxs,
+		MessageInfos:      file_pharmacy_v1_pharmacy_proto_msgTypes,
+	}.Build()
+	File_pharmacy_v1_pharmacy_proto = out.File
+	file_pharmacy_v1_pharmacy_proto_goTypes = nil
+	file_pharmacy_v1_pharmacy_proto_depIdxs = nil
+}
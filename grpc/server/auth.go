@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ayubmalik/go-cookbook/grpc/pharmacypb"
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// writeMethods are the calls that change pharmacies and need auth.ScopeWrite.
+var writeMethods = map[string]bool{
+	pharmacypb.PharmacyService_Create_FullMethodName: true,
+}
+
+// AuthInterceptor requires a bearer token granting auth.ScopeWrite in the authorization
+// metadata for calls that change pharmacies, and allows anonymous reads. A missing or
+// invalid token is Unauthenticated and one without the scope is PermissionDenied.
+func AuthInterceptor(v *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !writeMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		var header string
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+			header = md.Get("authorization")[0]
+		}
+		claims, err := v.Authorize(header, auth.ScopeWrite)
+		if errors.Is(err, auth.ErrInsufficientScope) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(auth.NewContext(ctx, claims), req)
+	}
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/grpc/pharmacypb"
+	"github.com/ayubmalik/go-cookbook/grpc/server"
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := auth.Signer{Alg: auth.EdDSA, Key: private, TTL: time.Minute}
+	verifier := &auth.Verifier{Alg: auth.EdDSA, Key: public}
+	client := newClient(t, grpc.UnaryInterceptor(server.AuthInterceptor(verifier)))
+
+	withToken := func(t *testing.T, scopes ...string) context.Context {
+		t.Helper()
+		token, err := signer.Sign("someone", scopes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+
+	t.Run("anonymous read", func(t *testing.T) {
+		_, err := client.GetByCode(context.Background(), &pharmacypb.GetByCodeRequest{Code: "FA512"})
+		assertCode(t, err, codes.OK)
+	})
+
+	t.Run("anonymous create", func(t *testing.T) {
+		_, err := client.Create(context.Background(), &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "NEW1"}})
+		assertCode(t, err, codes.Unauthenticated)
+	})
+
+	t.Run("create without scope", func(t *testing.T) {
+		_, err := client.Create(withToken(t, "pharmacy:read"), &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "NEW1"}})
+		assertCode(t, err, codes.PermissionDenied)
+	})
+
+	t.Run("create with scope", func(t *testing.T) {
+		_, err := client.Create(withToken(t, auth.ScopeWrite), &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "NEW1"}})
+		assertCode(t, err, codes.OK)
+	})
+}
@@ -0,0 +1,129 @@
+// Package server implements the PharmacyService gRPC service on top of a pharmacy.Repo.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/ayubmalik/go-cookbook/grpc/pharmacypb"
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// searchPageSize is the number of pharmacies read from the Repo at a time while streaming
+// a postcode search.
+const searchPageSize = 100
+
+// Server serves a pharmacy.Repo as a PharmacyService.
+type Server struct {
+	pharmacypb.UnimplementedPharmacyServiceServer
+	Repo pharmacy.Repo
+}
+
+// New returns a Server backed by repo.
+func New(repo pharmacy.Repo) *Server {
+	return &Server{Repo: repo}
+}
+
+func (s *Server) GetByCode(ctx context.Context, req *pharmacypb.GetByCodeRequest) (*pharmacypb.Pharmacy, error) {
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+	p, err := s.Repo.FindByCode(ctx, req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return ToProto(*p), nil
+}
+
+// SearchByPostcode pages through the Repo so the whole result is never held in memory.
+func (s *Server) SearchByPostcode(req *pharmacypb.SearchByPostcodeRequest, stream pharmacypb.PharmacyService_SearchByPostcodeServer) error {
+	ctx := stream.Context()
+	cursor := ""
+	for {
+		page, err := s.Repo.FindByPostcodePage(ctx, req.GetPostcode(), cursor, searchPageSize)
+		if err != nil {
+			return toStatus(err)
+		}
+		for _, p := range page.Pharmacies {
+			if err := stream.Send(ToProto(*p)); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		cursor = page.Next
+	}
+}
+
+func (s *Server) Create(ctx context.Context, req *pharmacypb.CreateRequest) (*pharmacypb.Pharmacy, error) {
+	if req.GetPharmacy() == nil {
+		return nil, status.Error(codes.InvalidArgument, "pharmacy is required")
+	}
+	// normalize here too, as the Repo does so on its own copy, to return what was stored
+	p, err := pharmacy.Normalize(FromProto(req.GetPharmacy()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.Repo.Insert(ctx, p); err != nil {
+		return nil, toStatus(err)
+	}
+	return ToProto(p), nil
+}
+
+// toStatus maps a Repo error to a gRPC status. Any other error is logged, and only a
+// generic message returned, so driver and SQL details are not sent to clients.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, pharmacy.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, pharmacy.ErrDuplicateCode):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, pharmacy.ErrInvalidInput), errors.Is(err, pharmacy.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		log.Printf("server: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// ToProto converts p to its protobuf message.
+func ToProto(p pharmacy.Pharmacy) *pharmacypb.Pharmacy {
+	pb := &pharmacypb.Pharmacy{
+		Code:       p.Code,
+		Name:       p.Name,
+		AddrLine_1: pharmacy.StringPtr(p.AddrLine1),
+		AddrLine_2: pharmacy.StringPtr(p.AddrLine2),
+		AddrLine_3: pharmacy.StringPtr(p.AddrLine3),
+		AddrLine_4: pharmacy.StringPtr(p.AddrLine4),
+		Postcode:   p.Postcode,
+		Phone:      pharmacy.StringPtr(p.Phone),
+	}
+	if p.LatLng != nil {
+		pb.LatLng = &pharmacypb.LatLng{Lat: p.LatLng.Lat, Lng: p.LatLng.Lng}
+	}
+	return pb
+}
+
+// FromProto converts pb to a pharmacy.Pharmacy.
+func FromProto(pb *pharmacypb.Pharmacy) pharmacy.Pharmacy {
+	p := pharmacy.Pharmacy{
+		Code:      pb.GetCode(),
+		Name:      pb.GetName(),
+		AddrLine1: pharmacy.NullStringPtr(pb.AddrLine_1),
+		AddrLine2: pharmacy.NullStringPtr(pb.AddrLine_2),
+		AddrLine3: pharmacy.NullStringPtr(pb.AddrLine_3),
+		AddrLine4: pharmacy.NullStringPtr(pb.AddrLine_4),
+		Postcode:  pb.GetPostcode(),
+		Phone:     pharmacy.NullStringPtr(pb.Phone),
+	}
+	if ll := pb.GetLatLng(); ll != nil {
+		p.LatLng = &pharmacy.LatLng{Lat: ll.GetLat(), Lng: ll.GetLng()}
+	}
+	return p
+}
@@ -0,0 +1,179 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/grpc/pharmacypb"
+	"github.com/ayubmalik/go-cookbook/grpc/server"
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/memory"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pharmacytest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient serves the fixtures over an in-process bufconn listener and returns a client
+// connected to it.
+func newClient(t *testing.T, opts ...grpc.ServerOption) pharmacypb.PharmacyServiceClient {
+	t.Helper()
+	repo, err := memory.New(pharmacytest.Pharmacies()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	pharmacypb.RegisterPharmacyServiceServer(srv, server.New(repo))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pharmacypb.NewPharmacyServiceClient(conn)
+}
+
+func TestGetByCode(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+
+	t.Run("found", func(t *testing.T) {
+		got, err := client.GetByCode(ctx, &pharmacypb.GetByCodeRequest{Code: "FA512"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetName() != "Lords Pharmacy" {
+			t.Errorf("got: %s, but want %s", got.GetName(), "Lords Pharmacy")
+		}
+		// an empty address line is not the same as NULL
+		if got.AddrLine_1 == nil || got.AddrLine_3 != nil {
+			t.Errorf("got: %v %v, but want empty string and nil", got.AddrLine_1, got.AddrLine_3)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.GetByCode(ctx, &pharmacypb.GetByCodeRequest{Code: "XX000"})
+		assertCode(t, err, codes.NotFound)
+	})
+
+	t.Run("no code", func(t *testing.T) {
+		_, err := client.GetByCode(ctx, &pharmacypb.GetByCodeRequest{})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+}
+
+func TestSearchByPostcode(t *testing.T) {
+	client := newClient(t)
+	stream, err := client.SearchByPostcode(context.Background(), &pharmacypb.SearchByPostcodeRequest{Postcode: "LE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for {
+		p, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p.GetCode())
+	}
+
+	want := []string{"FD294", "FC826", "ZZ999"}
+	if len(got) != len(want) {
+		t.Fatalf("got: %v, but want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got: %v, but want %v", got, want)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+
+	t.Run("created", func(t *testing.T) {
+		want := &pharmacypb.Pharmacy{Code: "NEW1", Name: "New", Postcode: "LE1 1AA",
+			LatLng: &pharmacypb.LatLng{Lat: 52.5, Lng: -1.1}}
+		if _, err := client.Create(ctx, &pharmacypb.CreateRequest{Pharmacy: want}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := client.GetByCode(ctx, &pharmacypb.GetByCodeRequest{Code: "NEW1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetName() != want.GetName() || got.GetLatLng().GetLat() != want.GetLatLng().GetLat() {
+			t.Errorf("got: %v, but want %v", got, want)
+		}
+	})
+
+	t.Run("normalized", func(t *testing.T) {
+		got, err := client.Create(ctx, &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "NEW2", Postcode: "le15ab"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetPostcode() != "LE1 5AB" {
+			t.Errorf("got: %q, but want the stored postcode %q", got.GetPostcode(), "LE1 5AB")
+		}
+	})
+
+	t.Run("duplicate code", func(t *testing.T) {
+		_, err := client.Create(ctx, &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "FA512"}})
+		assertCode(t, err, codes.AlreadyExists)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := client.Create(ctx, &pharmacypb.CreateRequest{Pharmacy: &pharmacypb.Pharmacy{Code: "TOOLONG"}})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+
+	t.Run("no pharmacy", func(t *testing.T) {
+		_, err := client.Create(ctx, &pharmacypb.CreateRequest{})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+}
+
+// brokenRepo fails every FindByCode with an error carrying driver details.
+type brokenRepo struct {
+	pharmacy.Repo
+}
+
+func (brokenRepo) FindByCode(context.Context, string) (*pharmacy.Pharmacy, error) {
+	return nil, errors.New(`pq: relation "pharmacy" does not exist`)
+}
+
+func TestInternalError(t *testing.T) {
+	_, err := server.New(brokenRepo{}).GetByCode(context.Background(), &pharmacypb.GetByCodeRequest{Code: "FA512"})
+	assertCode(t, err, codes.Internal)
+	if got := status.Convert(err).Message(); got != "internal error" {
+		t.Errorf("got: %q, but want %q", got, "internal error")
+	}
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Errorf("got: %v (%v), but want %v", got, err, want)
+	}
+}
@@ -6,39 +6,32 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"time"
+
+	"github.com/ayubmalik/go-cookbook/config"
+	"github.com/ayubmalik/mqtt-aws/settings"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
 func main() {
-	if err := run(os.Args, os.Stdout); err != nil {
+	if err := run(os.Args, os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
 		log.Fatalln(err)
 	}
 }
 
 func run(args []string, stdout io.Writer) error {
-	var (
-		flags    = flag.NewFlagSet(args[0], flag.ExitOnError)
-		host     = flags.String("host", "", "the host/endpoint")
-		keyFile  = flags.String("keyfile", "", "The private key file in PEM format")
-		certFile = flags.String("certfile", "", "The certificate file in PEM format")
-		caFile   = flags.String("cafile", "", "The CA certificate file in PEM format")
-		topic    = flags.String("topic", "topic_1", "The MQTT topic for pub/sub")
-	)
-	if err := flags.Parse(args[1:]); err != nil {
+	s := settings.Default()
+	if _, err := config.Load(&s, args[0], args[1:]); err != nil {
 		return err
 	}
-
-	if *host == "" || *keyFile == "" || *certFile == "" {
-		return errors.New("host, keyFile and certFile are required")
+	if err := config.Print(stdout, &s); err != nil {
+		return err
 	}
 
-	log.Printf("host=%s, keyFile=%s, certFile=%s, caFile=%s\n", *host, *keyFile, *certFile, *caFile)
-
 	// MQTT specific stuff
 	var messagePubHandler mqtt.MessageHandler = func(client mqtt.Client, msg mqtt.Message) {
 		log.Printf("Received message: %s from topic: %s\n", msg.Payload(), msg.Topic())
@@ -52,13 +45,13 @@ func run(args []string, stdout io.Writer) error {
 		log.Printf("Connect lost: %v", err)
 	}
 
-	tlsConfig, err := NewTLSConfig(*caFile, *certFile, *keyFile)
+	tlsConfig, err := NewTLSConfig(s.CAFile, s.CertFile, s.KeyFile)
 	if err != nil {
 		panic(err)
 	}
 
 	opts := mqtt.NewClientOptions()
-	opts.AddBroker(fmt.Sprintf("tls://%s:%d", *host, 8883))
+	opts.AddBroker(fmt.Sprintf("tls://%s:%d", s.Host, s.Port))
 	opts.SetClientID("basicPubSub")
 	opts.SetTLSConfig(tlsConfig)
 	opts.SetDefaultPublishHandler(messagePubHandler)
@@ -71,7 +64,7 @@ func run(args []string, stdout io.Writer) error {
 		log.Fatalln(token.Error())
 	}
 
-	sub(client, *topic)
+	sub(client, s.Topic)
 	time.Sleep(11 * time.Second)
 	client.Disconnect(250)
 	return nil
@@ -1,11 +1,18 @@
 module github.com/ayubmalik/mqtt-aws
 
-go 1.17
+go 1.22
 
-require github.com/eclipse/paho.mqtt.golang v1.4.1
+require (
+	github.com/ayubmalik/go-cookbook/config v0.0.0-00010101000000-000000000000
+	github.com/eclipse/paho.mqtt.golang v1.4.1
+)
 
 require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
 	golang.org/x/net v0.0.0-20220811182439-13a9a731de15 // indirect
 	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
@@ -0,0 +1,28 @@
+// Package settings holds the settings shared by the pub and sub commands.
+package settings
+
+import "errors"
+
+// Settings are loaded by the config package from, in increasing priority, the defaults,
+// a -config file, MQTT_* env variables and flags.
+type Settings struct {
+	Host     string `config:"host" env:"MQTT_HOST" usage:"the host/endpoint"`
+	Port     int    `config:"port" env:"MQTT_PORT" usage:"the MQTT over TLS port"`
+	KeyFile  string `config:"keyfile" env:"MQTT_KEYFILE" usage:"The private key file in PEM format"`
+	CertFile string `config:"certfile" env:"MQTT_CERTFILE" usage:"The certificate file in PEM format"`
+	CAFile   string `config:"cafile" env:"MQTT_CAFILE" usage:"The CA certificate file in PEM format"`
+	Topic    string `config:"topic" env:"MQTT_TOPIC" usage:"The MQTT topic for pub/sub"`
+}
+
+// Default returns the AWS IoT TLS port and the default topic.
+func Default() Settings {
+	return Settings{Port: 8883, Topic: "topic_1"}
+}
+
+// Validate checks the settings needed to connect are set.
+func (s Settings) Validate() error {
+	if s.Host == "" || s.KeyFile == "" || s.CertFile == "" {
+		return errors.New("host, keyfile and certfile are required")
+	}
+	return nil
+}
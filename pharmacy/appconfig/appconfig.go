@@ -0,0 +1,50 @@
+// Package appconfig is the configuration shared by the sql examples, loaded with the
+// config module.
+package appconfig
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/cache"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/ayubmalik/go-cookbook/pharmacy/retry"
+)
+
+// Config is the configuration shared by the sql examples, which embed it in their own
+// to add settings such as pgsql.PoolSettings.
+type Config struct {
+	DB              pgsql.ConnConfig `config:"db"`
+	Timeout         time.Duration    `config:"timeout" env:"DB_TIMEOUT" usage:"the statement timeout, 0 for none"`
+	RetryMaxElapsed time.Duration    `config:"retry_max_elapsed" env:"DB_RETRY_MAX_ELAPSED" usage:"how long to retry transient errors for, 0 until cancelled"`
+	Cache           cache.Settings   `config:"cache"`
+}
+
+// DefaultConfig returns the defaults for a local postgres. There is no default password
+// and TLS is required, so a local database without TLS must set sslmode disable.
+func DefaultConfig() Config {
+	return Config{
+		DB: pgsql.ConnConfig{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "root",
+			Name:    "pharmacy",
+			SSLMode: pgsql.SSLRequire,
+		},
+		RetryMaxElapsed: retry.Default.MaxElapsedTime,
+		Cache:           cache.DefaultSettings(),
+	}
+}
+
+// Validate checks every part of c.
+func (c Config) Validate() error {
+	errs := []error{c.DB.Validate(), c.Cache.Validate()}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout %s must not be negative", c.Timeout))
+	}
+	if c.RetryMaxElapsed < 0 {
+		errs = append(errs, fmt.Errorf("retry_max_elapsed %s must not be negative", c.RetryMaxElapsed))
+	}
+	return errors.Join(errs...)
+}
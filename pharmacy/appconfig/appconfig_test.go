@@ -0,0 +1,41 @@
+package appconfig_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/config"
+	"github.com/ayubmalik/go-cookbook/pharmacy/appconfig"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	if err := appconfig.DefaultConfig().Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("DB_HOST", "db.example.com")
+	t.Setenv("DB_PASSWORD", "secret")
+
+	cfg := appconfig.DefaultConfig()
+	if _, err := config.Load(&cfg, "test", []string{"-db.sslmode", "disable", "-timeout", "5s"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "host='db.example.com' port=5432 user='root' dbname='pharmacy' sslmode='disable' password='secret'"
+	if got := cfg.DB.DSN(); got != want {
+		t.Errorf("got: %s, but want %s", got, want)
+	}
+	if cfg.Timeout != 5*time.Second {
+		t.Errorf("got: %s, but want %s", cfg.Timeout, 5*time.Second)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg := appconfig.DefaultConfig()
+		_, err := config.Load(&cfg, "test", []string{"-db.sslmode", "verify-full", "-timeout", "-1s"})
+		if err == nil || !strings.Contains(err.Error(), "needs db.sslrootcert") || !strings.Contains(err.Error(), "timeout -1s") {
+			t.Errorf("got: %v, but want the TLS and timeout errors", err)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+// Package auth issues and verifies the JWTs that authorise changes to pharmacies.
+//
+// Tokens are signed with HS256 (a shared secret), RS256 or EdDSA (Ed25519), and carry an
+// OAuth 2 style space separated scope claim. Writes need the ScopeWrite scope, reads are
+// anonymous.
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ScopeWrite is the scope needed to insert, update or delete pharmacies.
+const ScopeWrite = "pharmacy:write"
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// minSecretLen is the shortest HS256 secret accepted, the size of the SHA-256 output.
+const minSecretLen = 32
+
+// ErrInvalidToken is returned for a token that is malformed, has a bad signature or
+// fails claim validation.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrInsufficientScope is returned for a valid token that does not grant a needed scope.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// Claims are the registered claims plus the granted scopes.
+type Claims struct {
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Scopes returns the space separated scopes in the scope claim.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports if scope was granted.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Signer issues tokens. Key is a []byte secret for HS256, an *rsa.PrivateKey for RS256 or
+// an ed25519.PrivateKey for EdDSA.
+type Signer struct {
+	Alg      string
+	Key      interface{}
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+}
+
+// Sign returns a token for subject granting scopes, valid from now for the TTL.
+func (s *Signer) Sign(subject string, scopes ...string) (string, error) {
+	method, err := signingMethod(s.Alg)
+	if err != nil {
+		return "", err
+	}
+	if err := checkKey(s.Alg, s.Key, true); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Scope: strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    s.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.TTL)),
+		},
+	}
+	if s.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.Audience}
+	}
+	return jwt.NewWithClaims(method, claims).SignedString(s.Key)
+}
+
+// Verifier checks tokens. Key is a []byte secret for HS256, an *rsa.PublicKey for RS256 or
+// an ed25519.PublicKey for EdDSA. Only Alg is accepted, so an RS256 public key can never
+// be used as an HS256 secret. Issuer and Audience are checked when set, exp is always
+// required and nbf is checked when present, each allowing for Leeway of clock skew.
+type Verifier struct {
+	Alg      string
+	Key      interface{}
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// Verify parses token and returns its claims if it is valid.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	if _, err := signingMethod(v.Alg); err != nil {
+		return nil, err
+	}
+	if err := checkKey(v.Alg, v.Key, false); err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{v.Alg}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.Leeway),
+	}
+	if v.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.Issuer))
+	}
+	if v.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.Audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return v.Key, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// LoadSigningKey loads the Signer key for alg from path. For HS256 the file holds the
+// secret, otherwise a PEM encoded PKCS #8 private key, or PKCS #1 for RS256.
+func LoadSigningKey(alg, path string) (interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if alg == HS256 {
+		return secret(b)
+	}
+
+	block, err := decodePEM(b, path)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := checkKey(alg, key, true); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadVerifyingKey loads the Verifier key for alg from path. For HS256 the file holds the
+// secret, otherwise a PEM encoded PKIX public key or a certificate, or PKCS #1 for RS256.
+func LoadVerifyingKey(alg, path string) (interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if alg == HS256 {
+		return secret(b)
+	}
+
+	block, err := decodePEM(b, path)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := checkKey(alg, key, false); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case HS256:
+		return jwt.SigningMethodHS256, nil
+	case RS256:
+		return jwt.SigningMethodRS256, nil
+	case EdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q, want %s, %s or %s", alg, HS256, RS256, EdDSA)
+	}
+}
+
+// checkKey returns an error if key is not the type the jwt package expects for alg.
+func checkKey(alg string, key interface{}, private bool) error {
+	var ok bool
+	switch alg {
+	case HS256:
+		var b []byte
+		b, ok = key.([]byte)
+		if ok && len(b) < minSecretLen {
+			return fmt.Errorf("%s secret must be at least %d bytes", HS256, minSecretLen)
+		}
+	case RS256:
+		if private {
+			_, ok = key.(*rsa.PrivateKey)
+		} else {
+			_, ok = key.(*rsa.PublicKey)
+		}
+	case EdDSA:
+		if private {
+			_, ok = key.(ed25519.PrivateKey)
+		} else {
+			_, ok = key.(ed25519.PublicKey)
+		}
+	default:
+		_, err := signingMethod(alg)
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%T is not a %s key", key, alg)
+	}
+	return nil
+}
+
+func secret(b []byte) ([]byte, error) {
+	b = []byte(strings.TrimSpace(string(b)))
+	if len(b) < minSecretLen {
+		return nil, fmt.Errorf("%s secret must be at least %d bytes", HS256, minSecretLen)
+	}
+	return b, nil
+}
+
+func decodePEM(b []byte, path string) (*pem.Block, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,221 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// keyFiles writes a PEM private and public key pair for alg, or the same secret file twice
+// for HS256, and returns their paths.
+func keyFiles(t *testing.T, alg string) (private, public string) {
+	t.Helper()
+	dir := t.TempDir()
+	private, public = filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+
+	var priv, pub interface{}
+	switch alg {
+	case auth.HS256:
+		writeFile(t, private, []byte(testSecret+"\n"))
+		return private, private
+	case auth.RS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, pub = key, &key.PublicKey
+	case auth.EdDSA:
+		var err error
+		pub, priv, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, private, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	writeFile(t, public, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	return private, public
+}
+
+func writeFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newPair returns a Signer and Verifier for alg that agree on issuer and audience.
+func newPair(t *testing.T, alg string) (*auth.Signer, *auth.Verifier) {
+	t.Helper()
+	private, public := keyFiles(t, alg)
+	signKey, err := auth.LoadSigningKey(alg, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyKey, err := auth.LoadVerifyingKey(alg, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &auth.Signer{Alg: alg, Key: signKey, Issuer: "cookbook", Audience: "pharmacy", TTL: time.Minute}
+	verifier := &auth.Verifier{Alg: alg, Key: verifyKey, Issuer: "cookbook", Audience: "pharmacy"}
+	return signer, verifier
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, alg := range []string{auth.HS256, auth.RS256, auth.EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			signer, verifier := newPair(t, alg)
+			token, err := signer.Sign("someone", auth.ScopeWrite, "other")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != "someone" {
+				t.Errorf("got: %s, but want %s", claims.Subject, "someone")
+			}
+			if !claims.HasScope(auth.ScopeWrite) || claims.HasScope("pharmacy") {
+				t.Errorf("got scopes: %v, but want %s and other", claims.Scopes(), auth.ScopeWrite)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	signer, verifier := newPair(t, auth.RS256)
+
+	sign := func(t *testing.T, s auth.Signer) string {
+		t.Helper()
+		token, err := s.Sign("someone", auth.ScopeWrite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	otherSigner, _ := newPair(t, auth.RS256)
+	hsSigner, _ := newPair(t, auth.HS256)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, auth.Signer{Alg: signer.Alg, Key: signer.Key, Issuer: "cookbook", Audience: "pharmacy", TTL: -time.Hour})},
+		{"wrong issuer", sign(t, auth.Signer{Alg: signer.Alg, Key: signer.Key, Issuer: "elsewhere", Audience: "pharmacy", TTL: time.Minute})},
+		{"wrong audience", sign(t, auth.Signer{Alg: signer.Alg, Key: signer.Key, Issuer: "cookbook", Audience: "other", TTL: time.Minute})},
+		{"no audience", sign(t, auth.Signer{Alg: signer.Alg, Key: signer.Key, Issuer: "cookbook", TTL: time.Minute})},
+		{"wrong key", sign(t, *otherSigner)},
+		{"wrong algorithm", sign(t, *hsSigner)},
+		{"malformed", "not.a.token"},
+		{"tampered", sign(t, *signer) + "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.token)
+			if !errors.Is(err, auth.ErrInvalidToken) {
+				t.Errorf("got: %v, but want %v", err, auth.ErrInvalidToken)
+			}
+		})
+	}
+
+	t.Run("not yet valid", func(t *testing.T) {
+		now := time.Now()
+		claims := auth.Claims{
+			Scope: auth.ScopeWrite,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "cookbook",
+				Audience:  jwt.ClaimStrings{"pharmacy"},
+				NotBefore: jwt.NewNumericDate(now.Add(time.Hour)),
+				ExpiresAt: jwt.NewNumericDate(now.Add(2 * time.Hour)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signer.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifier.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got: %v, but want %v", err, auth.ErrInvalidToken)
+		}
+	})
+
+	t.Run("no expiry", func(t *testing.T) {
+		claims := auth.Claims{
+			Scope:            auth.ScopeWrite,
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "cookbook", Audience: jwt.ClaimStrings{"pharmacy"}},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signer.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifier.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("got: %v, but want %v", err, auth.ErrInvalidToken)
+		}
+	})
+}
+
+func TestLoadKeys(t *testing.T) {
+	rsaPrivate, rsaPublic := keyFiles(t, auth.RS256)
+	edPrivate, _ := keyFiles(t, auth.EdDSA)
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short")
+	writeFile(t, short, []byte("too short"))
+	notPEM := filepath.Join(dir, "not.pem")
+	writeFile(t, notPEM, []byte(strings.Repeat("x", 64)))
+
+	t.Run("wrong key type", func(t *testing.T) {
+		if _, err := auth.LoadSigningKey(auth.EdDSA, rsaPrivate); err == nil {
+			t.Error("got no error loading an RSA key for EdDSA")
+		}
+		if _, err := auth.LoadSigningKey(auth.RS256, edPrivate); err == nil {
+			t.Error("got no error loading an Ed25519 key for RS256")
+		}
+	})
+
+	t.Run("public key as private", func(t *testing.T) {
+		if _, err := auth.LoadSigningKey(auth.RS256, rsaPublic); err == nil {
+			t.Error("got no error loading a public key to sign with")
+		}
+	})
+
+	t.Run("short secret", func(t *testing.T) {
+		if _, err := auth.LoadVerifyingKey(auth.HS256, short); err == nil {
+			t.Error("got no error for a short secret")
+		}
+	})
+
+	t.Run("not PEM", func(t *testing.T) {
+		if _, err := auth.LoadVerifyingKey(auth.RS256, notPEM); err == nil {
+			t.Error("got no error for a file without PEM data")
+		}
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		if _, err := auth.LoadVerifyingKey("none", rsaPublic); err == nil {
+			t.Error("got no error for alg none")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Run implements the `token` command shared by the sql examples, which prints a signed
+// token for calling the write endpoints of `serve`.
+func Run(args []string, stdout io.Writer) error {
+	var (
+		flags    = flag.NewFlagSet("token", flag.ExitOnError)
+		alg      = flags.String("alg", RS256, "the signing algorithm, HS256, RS256 or EdDSA")
+		keyFile  = flags.String("key", "", "the HS256 secret file, or the private key file in PEM format")
+		subject  = flags.String("sub", "", "the subject the token is issued to")
+		scope    = flags.String("scope", ScopeWrite, "the space separated scopes to grant")
+		issuer   = flags.String("iss", "", "the issuer claim")
+		audience = flags.String("aud", "", "the audience claim")
+		ttl      = flags.Duration("ttl", time.Hour, "how long the token is valid for")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return errors.New("usage: token -key file [-alg RS256] [-sub subject] [-scope scopes] [-iss issuer] [-aud audience] [-ttl 1h]")
+	}
+
+	key, err := LoadSigningKey(*alg, *keyFile)
+	if err != nil {
+		return err
+	}
+	signer := Signer{Alg: *alg, Key: key, Issuer: *issuer, Audience: *audience, TTL: *ttl}
+	token, err := signer.Sign(*subject, *scope)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, token)
+	return err
+}
+
+// VerifierFlags registers the flags a command needs to build a Verifier on flags. The
+// returned function builds it after parsing, or returns nil if no key file was given.
+func VerifierFlags(flags *flag.FlagSet) func() (*Verifier, error) {
+	var (
+		alg      = flags.String("jwt-alg", RS256, "the JWT signing algorithm, HS256, RS256 or EdDSA")
+		keyFile  = flags.String("jwt-key", "", "the HS256 secret file, or the public key or certificate file in PEM format")
+		issuer   = flags.String("jwt-iss", "", "the required JWT issuer")
+		audience = flags.String("jwt-aud", "", "the required JWT audience")
+		leeway   = flags.Duration("jwt-leeway", time.Minute, "the allowed clock skew for exp and nbf")
+	)
+	return func() (*Verifier, error) {
+		if *keyFile == "" {
+			return nil, nil
+		}
+		key, err := LoadVerifyingKey(*alg, *keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &Verifier{Alg: *alg, Key: key, Issuer: *issuer, Audience: *audience, Leeway: *leeway}, nil
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying claims, with their subject as the actor
+// recorded in the history of the pharmacies changed with ctx.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	if claims.Subject != "" {
+		ctx = pharmacy.WithActor(ctx, claims.Subject)
+	}
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the claims of the authenticated caller, if there is one.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// BearerToken returns the token in an "Authorization: Bearer <token>" header value.
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// Authorize checks the token in an Authorization header value grants scope. It returns
+// ErrInvalidToken for a missing or invalid token and ErrInsufficientScope if the token is
+// valid but does not grant scope.
+func (v *Verifier) Authorize(header, scope string) (*Claims, error) {
+	token, ok := BearerToken(header)
+	if !ok {
+		return nil, fmt.Errorf("%w: bearer token required", ErrInvalidToken)
+	}
+	claims, err := v.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasScope(scope) {
+		return nil, fmt.Errorf("%w: %s", ErrInsufficientScope, scope)
+	}
+	return claims, nil
+}
+
+// Middleware allows anonymous GET, HEAD and OPTIONS requests and requires a bearer token
+// granting ScopeWrite for anything else, which changes pharmacies. Failures are 401 for a
+// missing or invalid token and 403 for a token without the scope, with a JSON error body
+// and a WWW-Authenticate header as in RFC 6750. The claims of an authorised request are
+// available from FromContext.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := v.Authorize(r.Header.Get("Authorization"), ScopeWrite)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusUnauthorized
+	challenge := `Bearer error="invalid_token"`
+	if errors.Is(err, ErrInsufficientScope) {
+		code = http.StatusForbidden
+		challenge = fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, ScopeWrite)
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
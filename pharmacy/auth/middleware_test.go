@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	signer, verifier := newPair(t, auth.EdDSA)
+	writeToken, err := signer.Sign("writer", auth.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readToken, err := signer.Sign("reader", "pharmacy:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotSubject, gotActor string
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSubject = ""
+		if claims, ok := auth.FromContext(r.Context()); ok {
+			gotSubject = claims.Subject
+		}
+		gotActor, _ = pharmacy.ActorFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		auth        string
+		wantStatus  int
+		wantSubject string
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK, ""},
+		{"anonymous write", http.MethodPost, "", http.StatusUnauthorized, ""},
+		{"not bearer", http.MethodPost, "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"invalid token", http.MethodPut, "Bearer not.a.token", http.StatusUnauthorized, ""},
+		{"no write scope", http.MethodDelete, "Bearer " + readToken, http.StatusForbidden, ""},
+		{"write scope", http.MethodPost, "Bearer " + writeToken, http.StatusOK, "writer"},
+		{"lower case scheme", http.MethodPut, "bearer " + writeToken, http.StatusOK, "writer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/pharmacies", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got: %d, but want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && gotSubject != tt.wantSubject {
+				t.Errorf("got subject: %q, but want %q", gotSubject, tt.wantSubject)
+			}
+			// changes are recorded in the pharmacy history as made by the subject
+			if tt.wantStatus == http.StatusOK && gotActor != tt.wantSubject {
+				t.Errorf("got actor: %q, but want %q", gotActor, tt.wantSubject)
+			}
+			if rec.Code >= 400 && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("got no WWW-Authenticate header")
+			}
+		})
+	}
+}
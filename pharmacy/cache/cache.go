@@ -0,0 +1,309 @@
+// Package cache is a read-through cache of FindByCode for a pharmacy.Repo, as pharmacy
+// data changes rarely but is looked up by code all the time.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/metrics"
+)
+
+// Settings bound a cache, tagged for the config package.
+type Settings struct {
+	Size        int           `config:"size" env:"CACHE_SIZE" usage:"the most codes to cache, 0 to disable the cache"`
+	TTL         time.Duration `config:"ttl" env:"CACHE_TTL" usage:"how long a pharmacy is cached"`
+	NegativeTTL time.Duration `config:"negative_ttl" env:"CACHE_NEGATIVE_TTL" usage:"how long an unknown code is cached, 0 for not at all"`
+}
+
+// DefaultSettings caches up to 10000 pharmacies for 5 minutes and unknown codes for 30
+// seconds.
+func DefaultSettings() Settings {
+	return Settings{Size: 10000, TTL: 5 * time.Minute, NegativeTTL: 30 * time.Second}
+}
+
+// Validate checks the settings are not negative and a cache has a TTL.
+func (s Settings) Validate() error {
+	var errs []error
+	if s.Size < 0 {
+		errs = append(errs, fmt.Errorf("cache.size %d must not be negative", s.Size))
+	}
+	if s.TTL < 0 {
+		errs = append(errs, fmt.Errorf("cache.ttl %s must not be negative", s.TTL))
+	}
+	if s.Size > 0 && s.TTL == 0 {
+		errs = append(errs, errors.New("cache.ttl is required for a cache.size above 0"))
+	}
+	if s.NegativeTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache.negative_ttl %s must not be negative", s.NegativeTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// Stats counts the lookups of a Repo. Every FindByCode is a hit, a miss that queried the
+// repo, or a wait for the query of a concurrent miss for the same code.
+type Stats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Waits        uint64
+	Evictions    uint64
+	Entries      int
+}
+
+// Repo caches the FindByCode results of a pharmacy.Repo, including ErrNotFound for
+// NegativeTTL, in a least recently used cache of Size codes. Concurrent misses for the
+// same code share one query. Other errors are not cached.
+//
+// Insert, Update, Delete and Upsert invalidate the code they write, even if they fail as
+// the write may still have happened. Writes made around the Repo, by another process or
+// in a transaction of the backend, are seen once the entry expires, or sooner if they are
+// passed to Invalidate, e.g. by Follow. Every other call goes straight to the repo.
+type Repo struct {
+	pharmacy.Repo
+	settings Settings
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	calls   map[string]*call
+	stats   Stats
+}
+
+var _ pharmacy.Repo = (*Repo)(nil)
+
+// entry is a cached FindByCode result, the value of an element of the lru list.
+type entry struct {
+	code    string
+	p       *pharmacy.Pharmacy
+	err     error
+	expires time.Time
+}
+
+// call is a FindByCode query in flight, waited for by concurrent misses for its code.
+type call struct {
+	done chan struct{}
+	p    *pharmacy.Pharmacy
+	err  error
+	// stale is set if the code is invalidated during the query, so its result is not
+	// cached.
+	stale bool
+}
+
+// NewRepo returns repo cached with settings. A Size of 0 disables the cache.
+func NewRepo(repo pharmacy.Repo, settings Settings) *Repo {
+	return &Repo{
+		Repo:     repo,
+		settings: settings,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		calls:    make(map[string]*call),
+	}
+}
+
+// FindByCode returns the cached result for code, or queries the repo and caches it. A
+// query shared with a concurrent miss whose context was done before it finished is
+// retried.
+func (r *Repo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	if r.settings.Size <= 0 {
+		return r.Repo.FindByCode(ctx, code)
+	}
+
+	for {
+		r.mu.Lock()
+		if e, ok := r.lookup(code); ok {
+			if e.err != nil {
+				r.stats.NegativeHits++
+			} else {
+				r.stats.Hits++
+			}
+			r.mu.Unlock()
+			return result(e.p, e.err)
+		}
+
+		c, ok := r.calls[code]
+		if !ok {
+			c = &call{done: make(chan struct{})}
+			r.calls[code] = c
+			r.stats.Misses++
+			r.mu.Unlock()
+
+			r.load(ctx, code, c)
+			return result(c.p, c.err)
+		}
+		r.stats.Waits++
+		r.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextError(c.err) && ctx.Err() == nil {
+			continue
+		}
+		return result(c.p, c.err)
+	}
+}
+
+// load queries the repo for code on behalf of c and caches the result.
+func (r *Repo) load(ctx context.Context, code string, c *call) {
+	defer close(c.done)
+	c.p, c.err = r.Repo.FindByCode(ctx, code)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls[code] == c {
+		delete(r.calls, code)
+	}
+	if c.stale {
+		return
+	}
+	switch {
+	case c.err == nil:
+		r.store(&entry{code: code, p: c.p, expires: time.Now().Add(r.settings.TTL)})
+	case errors.Is(c.err, pharmacy.ErrNotFound) && r.settings.NegativeTTL > 0:
+		r.store(&entry{code: code, err: c.err, expires: time.Now().Add(r.settings.NegativeTTL)})
+	}
+}
+
+// lookup returns the unexpired entry for code, marking it most recently used. The caller
+// must hold the lock.
+func (r *Repo) lookup(code string) (*entry, bool) {
+	el, ok := r.entries[code]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !time.Now().Before(e.expires) {
+		r.lru.Remove(el)
+		delete(r.entries, code)
+		return nil, false
+	}
+	r.lru.MoveToFront(el)
+	return e, true
+}
+
+// store caches e, evicting the least recently used entry if the cache is full. The caller
+// must hold the lock.
+func (r *Repo) store(e *entry) {
+	if el, ok := r.entries[e.code]; ok {
+		el.Value = e
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.entries[e.code] = r.lru.PushFront(e)
+	if r.lru.Len() > r.settings.Size {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*entry).code)
+		r.stats.Evictions++
+	}
+}
+
+// Invalidate removes code from the cache, and stops a query for it in flight from being
+// cached, so the next FindByCode queries the repo.
+func (r *Repo) Invalidate(code string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.entries[code]; ok {
+		r.lru.Remove(el)
+		delete(r.entries, code)
+	}
+	if c, ok := r.calls[code]; ok {
+		c.stale = true
+		delete(r.calls, code)
+	}
+}
+
+// Clear removes every code from the cache, like Invalidate.
+func (r *Repo) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = make(map[string]*list.Element)
+	r.lru.Init()
+	for _, c := range r.calls {
+		c.stale = true
+	}
+	r.calls = make(map[string]*call)
+}
+
+// Follow invalidates the code of every event, such as those sent by the Watch of the pgx
+// backends, and clears the cache on a pharmacy.OpResync, until events is closed.
+func (r *Repo) Follow(events <-chan pharmacy.ChangeEvent) {
+	for e := range events {
+		if e.Operation == pharmacy.OpResync {
+			r.Clear()
+			continue
+		}
+		r.Invalidate(e.Code)
+	}
+}
+
+func (r *Repo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	defer r.Invalidate(p.Code)
+	return r.Repo.Insert(ctx, p)
+}
+
+func (r *Repo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	defer r.Invalidate(p.Code)
+	return r.Repo.Update(ctx, p)
+}
+
+func (r *Repo) Delete(ctx context.Context, code string) error {
+	defer r.Invalidate(code)
+	return r.Repo.Delete(ctx, code)
+}
+
+func (r *Repo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	defer r.Invalidate(p.Code)
+	return r.Repo.Upsert(ctx, p)
+}
+
+// Stats returns the counts so far and the number of codes cached, including expired
+// entries that have not been looked up since.
+func (r *Repo) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.stats
+	s.Entries = r.lru.Len()
+	return s
+}
+
+// Metrics returns the Stats of r, for metrics.Handler.
+func (r *Repo) Metrics() []metrics.Metric {
+	s := r.Stats()
+	return []metrics.Metric{
+		{Name: "pharmacy_cache_hits_total", Help: "The total number of lookups answered with a cached pharmacy.", Type: metrics.Counter, Value: float64(s.Hits)},
+		{Name: "pharmacy_cache_negative_hits_total", Help: "The total number of lookups answered with a cached unknown code.", Type: metrics.Counter, Value: float64(s.NegativeHits)},
+		{Name: "pharmacy_cache_misses_total", Help: "The total number of lookups that queried the database.", Type: metrics.Counter, Value: float64(s.Misses)},
+		{Name: "pharmacy_cache_waits_total", Help: "The total number of lookups that waited for the query of a concurrent miss.", Type: metrics.Counter, Value: float64(s.Waits)},
+		{Name: "pharmacy_cache_evictions_total", Help: "The total number of entries evicted to make room.", Type: metrics.Counter, Value: float64(s.Evictions)},
+		{Name: "pharmacy_cache_entries", Help: "The number of codes cached.", Type: metrics.Gauge, Value: float64(s.Entries)},
+	}
+}
+
+// result returns a copy of p, so callers can not change the cached pharmacy, or err.
+func result(p *pharmacy.Pharmacy, err error) (*pharmacy.Pharmacy, error) {
+	if err != nil {
+		return nil, err
+	}
+	c := *p
+	if p.LatLng != nil {
+		ll := *p.LatLng
+		c.LatLng = &ll
+	}
+	return &c, nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
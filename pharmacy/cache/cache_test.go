@@ -0,0 +1,332 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/cache"
+	"github.com/ayubmalik/go-cookbook/pharmacy/memory"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pharmacytest"
+)
+
+// countingRepo counts the FindByCode calls, holding each until release is closed if it is
+// not nil, and fails them with err if it is set.
+type countingRepo struct {
+	pharmacy.Repo
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (r *countingRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		r.started <- struct{}{}
+		<-r.release
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Repo.FindByCode(ctx, code)
+}
+
+func newRepo(t *testing.T, settings cache.Settings) (*cache.Repo, *countingRepo) {
+	mem, err := memory.New(pharmacytest.Pharmacies()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingRepo{Repo: mem}
+	return cache.NewRepo(counting, settings), counting
+}
+
+// find looks up each code, failing the test on any error other than ErrNotFound.
+func find(t *testing.T, repo pharmacy.Repo, codes ...string) {
+	t.Helper()
+	for _, code := range codes {
+		if _, err := repo.FindByCode(context.Background(), code); err != nil && !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertCalls(t *testing.T, r *countingRepo, want int32) {
+	t.Helper()
+	if got := r.calls.Load(); got != want {
+		t.Errorf("got %d calls, but want %d", got, want)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	if err := cache.DefaultSettings().Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (cache.Settings{}).Validate(); err != nil {
+		t.Errorf("got: %v, but want a disabled cache to be valid", err)
+	}
+
+	err := cache.Settings{Size: 10, NegativeTTL: -time.Second}.Validate()
+	for _, want := range []string{"cache.ttl is required", "cache.negative_ttl -1s"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got: %v, but want %s", err, want)
+		}
+	}
+}
+
+func TestRepo(t *testing.T) {
+	ctx := context.Background()
+	settings := cache.DefaultSettings()
+
+	t.Run("conformance", func(t *testing.T) {
+		pharmacytest.Run(t, func(t *testing.T) pharmacy.Repo {
+			mem, err := memory.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cache.NewRepo(mem, settings)
+		}, memory.UnknownActor)
+	})
+
+	t.Run("hit", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		find(t, repo, "FA512", "FA512", "FA512")
+		assertCalls(t, counting, 1)
+
+		// changing a result does not change the cache
+		p, err := repo.FindByCode(ctx, "FA512")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Name = "changed"
+		p.LatLng.Lat = 0
+		p, err = repo.FindByCode(ctx, "FA512")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name == "changed" || p.LatLng.Lat == 0 {
+			t.Errorf("got: %+v, but want the cached pharmacy unchanged", p)
+		}
+
+		want := cache.Stats{Hits: 4, Misses: 1, Entries: 1}
+		if got := repo.Stats(); got != want {
+			t.Errorf("got: %+v, but want %+v", got, want)
+		}
+	})
+
+	t.Run("not found cached", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		for i := 0; i < 3; i++ {
+			if _, err := repo.FindByCode(ctx, "XX000"); !errors.Is(err, pharmacy.ErrNotFound) {
+				t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+			}
+		}
+		assertCalls(t, counting, 1)
+		if got := repo.Stats().NegativeHits; got != 2 {
+			t.Errorf("got %d negative hits, but want 2", got)
+		}
+
+		s := settings
+		s.NegativeTTL = 0
+		repo, counting = newRepo(t, s)
+		find(t, repo, "XX000", "XX000")
+		assertCalls(t, counting, 2)
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		counting.err = errors.New("connection reset")
+		if _, err := repo.FindByCode(ctx, "FA512"); !errors.Is(err, counting.err) {
+			t.Errorf("got: %v, but want %v", err, counting.err)
+		}
+		counting.err = nil
+		find(t, repo, "FA512")
+		assertCalls(t, counting, 2)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		s := settings
+		s.TTL, s.NegativeTTL = 20*time.Millisecond, 20*time.Millisecond
+		repo, counting := newRepo(t, s)
+		find(t, repo, "FA512", "XX000", "FA512", "XX000")
+		assertCalls(t, counting, 2)
+
+		time.Sleep(30 * time.Millisecond)
+		find(t, repo, "FA512", "XX000")
+		assertCalls(t, counting, 4)
+	})
+
+	t.Run("least recently used evicted", func(t *testing.T) {
+		s := settings
+		s.Size = 2
+		repo, counting := newRepo(t, s)
+		// FC826 evicts FD294 as FA512 was used since
+		find(t, repo, "FA512", "FD294", "FA512", "FC826")
+		assertCalls(t, counting, 3)
+		find(t, repo, "FA512", "FC826")
+		assertCalls(t, counting, 3)
+		find(t, repo, "FD294")
+		assertCalls(t, counting, 4)
+
+		if got := repo.Stats(); got.Evictions != 2 || got.Entries != 2 {
+			t.Errorf("got: %+v, but want 2 evictions and 2 entries", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		repo, counting := newRepo(t, cache.Settings{})
+		find(t, repo, "FA512", "FA512", "XX000")
+		assertCalls(t, counting, 3)
+	})
+
+	t.Run("concurrent misses collapsed", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		counting.started = make(chan struct{}, 1)
+		counting.release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		lookup := func() {
+			defer wg.Done()
+			p, err := repo.FindByCode(ctx, "FA512")
+			if err == nil && p.Code != "FA512" {
+				err = errors.New("got " + p.Code)
+			}
+			errs <- err
+		}
+		wg.Add(1)
+		go lookup()
+		<-counting.started
+		for i := 0; i < 9; i++ {
+			wg.Add(1)
+			go lookup()
+		}
+		// wait for the lookups to wait for the first
+		for repo.Stats().Waits < 9 {
+			time.Sleep(time.Millisecond)
+		}
+		close(counting.release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		assertCalls(t, counting, 1)
+	})
+
+	t.Run("cancelled wait", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		counting.started = make(chan struct{}, 1)
+		counting.release = make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = repo.FindByCode(ctx, "FA512")
+		}()
+		<-counting.started
+		defer func() {
+			close(counting.release)
+			<-done
+		}()
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := repo.FindByCode(ctx, "FA512"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got: %v, but want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("writes invalidate", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		p := pharmacytest.Pharmacies()[0]
+		find(t, repo, p.Code, "NEW1")
+
+		p.Name = "Renamed"
+		if err := repo.Update(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.FindByCode(ctx, p.Code)
+		if err != nil || got.Name != "Renamed" {
+			t.Errorf("got: %+v %v, but want the update", got, err)
+		}
+
+		if err := repo.Insert(ctx, pharmacy.Pharmacy{Code: "NEW1", Name: "New"}); err != nil {
+			t.Fatal(err)
+		}
+		find(t, repo, "NEW1")
+
+		if err := repo.Delete(ctx, p.Code); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FindByCode(ctx, p.Code); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+
+		if err := repo.Upsert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		find(t, repo, p.Code)
+
+		repo.Invalidate("NEW1")
+		find(t, repo, "NEW1")
+		assertCalls(t, counting, 7)
+	})
+
+	t.Run("invalidated during a miss", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		counting.started = make(chan struct{}, 1)
+		counting.release = make(chan struct{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			find(t, repo, "FA512")
+		}()
+		<-counting.started
+		repo.Invalidate("FA512")
+		close(counting.release)
+		<-done
+
+		// the result of the first lookup may be from before the invalidation so it is not
+		// cached
+		counting.release = nil
+		find(t, repo, "FA512")
+		assertCalls(t, counting, 2)
+	})
+	t.Run("follow", func(t *testing.T) {
+		repo, counting := newRepo(t, settings)
+		find(t, repo, "FA512", "FC826", "FD294")
+
+		events := make(chan pharmacy.ChangeEvent)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			repo.Follow(events)
+		}()
+		events <- pharmacy.ChangeEvent{Code: "FA512", Operation: pharmacy.OpUpdate}
+		events <- pharmacy.ChangeEvent{Code: "XX000", Operation: pharmacy.OpDelete}
+		close(events)
+		<-done
+
+		find(t, repo, "FA512", "FC826")
+		assertCalls(t, counting, 4)
+
+		events = make(chan pharmacy.ChangeEvent, 1)
+		events <- pharmacy.ChangeEvent{Operation: pharmacy.OpResync}
+		close(events)
+		repo.Follow(events)
+		if got := repo.Stats().Entries; got != 0 {
+			t.Errorf("got %d entries, but want the cache cleared", got)
+		}
+		find(t, repo, "FA512", "FC826", "FD294")
+		assertCalls(t, counting, 7)
+	})
+}
@@ -0,0 +1,133 @@
+package pharmacy
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVColumns is the column layout of data/sample-pharmacies.csv.
+var CSVColumns = []string{"code", "name", "addr_line_1", "addr_line_2", "addr_line_3", "addr_line_4",
+	"postcode", "phone", "lat", "lng"}
+
+// Rejection is a CSV line that could not be imported and why.
+type Rejection struct {
+	Line   int
+	Reason string
+}
+
+// ImportStats summarises a CSV import into a backend.
+type ImportStats struct {
+	Imported int64
+	Rejected []Rejection
+	Elapsed  time.Duration
+}
+
+// Print writes the rejected lines and a throughput summary to w.
+func (s ImportStats) Print(w io.Writer) {
+	for _, r := range s.Rejected {
+		_, _ = fmt.Fprintf(w, "rejected line %d: %s\n", r.Line, r.Reason)
+	}
+
+	rate := 0.0
+	if secs := s.Elapsed.Seconds(); secs > 0 {
+		rate = float64(s.Imported) / secs
+	}
+	_, _ = fmt.Fprintf(w, "imported %d rows, rejected %d rows in %s (%.0f rows/s)\n",
+		s.Imported, len(s.Rejected), s.Elapsed.Round(time.Millisecond), rate)
+}
+
+// CSVReader streams pharmacies from CSV in the CSVColumns layout. Rows that fail to
+// parse or validate are skipped and recorded in Rejected rather than stopping the import.
+// An optional header row is skipped.
+type CSVReader struct {
+	csv      *csv.Reader
+	line     int
+	Rejected []Rejection
+}
+
+// NewCSVReader returns a CSVReader reading from r.
+func NewCSVReader(r io.Reader) *CSVReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return &CSVReader{csv: cr}
+}
+
+// Next returns the next valid pharmacy, or io.EOF when the input is exhausted.
+func (r *CSVReader) Next() (*Pharmacy, error) {
+	for {
+		record, err := r.csv.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			r.Rejected = append(r.Rejected, Rejection{Line: parseErr.StartLine, Reason: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.csv.FieldPos(0)
+		if line == 1 && strings.EqualFold(record[0], CSVColumns[0]) {
+			continue
+		}
+
+		p, err := ParseCSVRecord(record)
+		if err != nil {
+			r.Rejected = append(r.Rejected, Rejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		r.line = line
+		return p, nil
+	}
+}
+
+// Line returns the line of the pharmacy last returned by Next, for rejecting it later.
+func (r *CSVReader) Line() int {
+	return r.line
+}
+
+// ParseCSVRecord validates a CSV record against the pharmacy table. Empty optional fields
+// are stored as NULL.
+func ParseCSVRecord(record []string) (*Pharmacy, error) {
+	if len(record) != len(CSVColumns) {
+		return nil, fmt.Errorf("expected %d fields but got %d", len(CSVColumns), len(record))
+	}
+
+	p := &Pharmacy{
+		Code:      record[0],
+		Name:      record[1],
+		AddrLine1: NullString(record[2]),
+		AddrLine2: NullString(record[3]),
+		AddrLine3: NullString(record[4]),
+		AddrLine4: NullString(record[5]),
+		Postcode:  record[6],
+		Phone:     NullString(record[7]),
+	}
+
+	if record[8] != "" || record[9] != "" {
+		lat, err := strconv.ParseFloat(record[8], 32)
+		if err != nil {
+			return nil, fmt.Errorf("lat %q is not a number", record[8])
+		}
+		lng, err := strconv.ParseFloat(record[9], 32)
+		if err != nil {
+			return nil, fmt.Errorf("lng %q is not a number", record[9])
+		}
+		p.LatLng = &LatLng{Lat: float32(lat), Lng: float32(lng)}
+	}
+
+	normalized, err := Normalize(*p)
+	if err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
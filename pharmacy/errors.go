@@ -0,0 +1,65 @@
+package pharmacy
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when no pharmacy has the requested code.
+	ErrNotFound = errors.New("pharmacy not found")
+
+	// ErrDuplicateCode is matched by a *DuplicateCodeError.
+	ErrDuplicateCode = errors.New("duplicate pharmacy code")
+
+	// ErrInvalidInput is matched by a *ConstraintError and returned for invalid search
+	// arguments.
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// DuplicateCodeError is returned by Insert when a pharmacy with the same code exists.
+// errors.Is(err, ErrDuplicateCode) reports true for it.
+type DuplicateCodeError struct {
+	Code string
+	// Err is the driver error the backend translated, if any.
+	Err error
+}
+
+func (e *DuplicateCodeError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrDuplicateCode, e.Code)
+}
+
+func (e *DuplicateCodeError) Is(target error) bool {
+	return target == ErrDuplicateCode
+}
+
+func (e *DuplicateCodeError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintError is returned for a pharmacy that does not fit the pharmacy table, such
+// as one without a code or with a name that is too long. errors.Is(err, ErrInvalidInput)
+// reports true for it.
+type ConstraintError struct {
+	// Column is empty when the database does not say which column was at fault, as for a
+	// value too long for its column.
+	Column string
+	Reason string
+	// Err is the driver error the backend translated, if any.
+	Err error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Column == "" {
+		return e.Reason
+	}
+	return e.Column + " " + e.Reason
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,59 @@
+package pharmacy
+
+import (
+	"fmt"
+	"math"
+)
+
+// EarthRadiusMeters is the mean radius of the earth used for haversine distances.
+const EarthRadiusMeters = 6371008.8
+
+// BoundingBox is a lat/lng rectangle enclosing every point within a radius of its centre.
+// It does not handle the antimeridian which is fine for UK data.
+type BoundingBox struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// NewBoundingBox returns the box around origin used to prefilter a FindNearest search.
+func NewBoundingBox(origin LatLng, radiusMeters float64) BoundingBox {
+	lat := float64(origin.Lat)
+	lng := float64(origin.Lng)
+	dLat := radiusMeters / EarthRadiusMeters * 180 / math.Pi
+
+	box := BoundingBox{MinLat: lat - dLat, MaxLat: lat + dLat, MinLng: -180, MaxLng: 180}
+	// near the poles a degree of longitude shrinks to nothing so search every longitude
+	if cosLat := math.Cos(lat * math.Pi / 180); box.MaxLat < 90 && box.MinLat > -90 && cosLat > 0 {
+		dLng := dLat / cosLat
+		box.MinLng = lng - dLng
+		box.MaxLng = lng + dLng
+	}
+	return box
+}
+
+// Contains reports whether ll is inside the box.
+func (b BoundingBox) Contains(ll LatLng) bool {
+	lat, lng := float64(ll.Lat), float64(ll.Lng)
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// Distance returns the great-circle distance in metres between a and b using the
+// haversine formula, the same calculation the postgres backends do in SQL.
+func Distance(a, b LatLng) float64 {
+	lat1 := float64(a.Lat) * math.Pi / 180
+	lat2 := float64(b.Lat) * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (float64(b.Lng) - float64(a.Lng)) * math.Pi / 180
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// CheckNearest validates the FindNearest search arguments, returning an error matching
+// ErrInvalidInput if they are not positive.
+func CheckNearest(radiusMeters float64, limit int) error {
+	if radiusMeters <= 0 || limit <= 0 {
+		return fmt.Errorf("%w: radius %v and limit %d must be positive", ErrInvalidInput, radiusMeters, limit)
+	}
+	return nil
+}
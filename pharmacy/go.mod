@@ -0,0 +1,15 @@
+module github.com/ayubmalik/go-cookbook/pharmacy
+
+go 1.22
+
+require (
+	github.com/ayubmalik/go-cookbook/config v0.0.0-00010101000000-000000000000
+	github.com/golang-jwt/jwt/v5 v5.2.3
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
@@ -0,0 +1,57 @@
+package pharmacy
+
+import (
+	"context"
+	"time"
+)
+
+// The operations recorded in the history of a pharmacy, named as postgres names trigger
+// events.
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+)
+
+// Change is one write recorded in the history of a pharmacy: who made it, when, and the
+// pharmacy before and after. Before is nil for an insert and After is nil for a delete.
+type Change struct {
+	ID        int64
+	Code      string
+	Operation string
+	Actor     string
+	ChangedAt time.Time
+	Before    *Pharmacy
+	After     *Pharmacy
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming who is making changes, such as the subject of a
+// JWT. Repos record it as the Actor of every Change written with ctx.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, if there is one.
+func ActorFrom(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// AsOf returns the pharmacy as it was at a time from its history, oldest first, or
+// ErrNotFound if it had not been inserted yet or had been deleted.
+func AsOf(history []*Change, at time.Time) (*Pharmacy, error) {
+	var last *Change
+	for _, c := range history {
+		if c.ChangedAt.After(at) {
+			break
+		}
+		last = c
+	}
+	if last == nil || last.After == nil {
+		return nil, ErrNotFound
+	}
+	p := *last.After
+	return &p, nil
+}
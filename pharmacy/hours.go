@@ -0,0 +1,191 @@
+package pharmacy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	// embed the time zone database so London loads without zoneinfo on the host
+	_ "time/tzdata"
+)
+
+// DateLayout is the layout of the date of an Override.
+const DateLayout = "2006-01-02"
+
+// London is the time zone opening hours are kept in, so a session opening at 09:00 opens
+// at 09:00 GMT in winter and 09:00 BST in summer.
+var London = mustLoadLocation("Europe/London")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// ClockTime is a time of day as minutes after midnight, from 00:00 to 24:00.
+type ClockTime int
+
+// EndOfDay is 24:00, the close of a session open until midnight.
+const EndOfDay ClockTime = 24 * 60
+
+// Clock returns the ClockTime hour:minute.
+func Clock(hour, minute int) ClockTime {
+	return ClockTime(hour*60 + minute)
+}
+
+// ParseClockTime parses a time of day such as 08:30 or 24:00.
+func ParseClockTime(s string) (ClockTime, error) {
+	if s == EndOfDay.String() {
+		return EndOfDay, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q is not HH:MM between 00:00 and 24:00", ErrInvalidInput, s)
+	}
+	return Clock(t.Hour(), t.Minute()), nil
+}
+
+// String returns c as HH:MM.
+func (c ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", c/60, c%60)
+}
+
+// Session is a period a pharmacy is open, starting on one day. A session that closes at
+// or before it opens closes the next day, e.g. 22:00 to 02:00, so 00:00 to 00:00 is open
+// for 24 hours.
+type Session struct {
+	Opens  ClockTime
+	Closes ClockTime
+}
+
+// Overnight reports if s closes the day after it opens.
+func (s Session) Overnight() bool {
+	return s.Closes <= s.Opens
+}
+
+// end returns when s closes on the day it opens.
+func (s Session) end() ClockTime {
+	if s.Overnight() {
+		return EndOfDay
+	}
+	return s.Closes
+}
+
+func (s Session) String() string {
+	return s.Opens.String() + "-" + s.Closes.String()
+}
+
+// Override replaces the weekly sessions on a date, such as a bank holiday. No sessions
+// means closed all day.
+type Override struct {
+	// Date is the day in London, see DateLayout.
+	Date     string
+	Sessions []Session
+}
+
+// OpeningHours is when a pharmacy is open in London time: sessions for each day of the
+// week, indexed by time.Weekday, and overrides for particular dates. A session that
+// crosses midnight belongs to the day it opens, so on a date that is overridden the
+// pharmacy is still open until an overnight session of the day before closes.
+type OpeningHours struct {
+	Weekly    [7][]Session
+	Overrides []Override
+}
+
+// SessionsOn returns the sessions opening on date, a time in London, from its override
+// if it has one, otherwise from the weekly sessions.
+func (h OpeningHours) SessionsOn(date time.Time) []Session {
+	day := date.Format(DateLayout)
+	for _, o := range h.Overrides {
+		if o.Date == day {
+			return o.Sessions
+		}
+	}
+	return h.Weekly[date.Weekday()]
+}
+
+// IsOpen reports if the pharmacy is open at t: in a session that opened earlier the same
+// day in London, or in an overnight session that opened the day before.
+func (h OpeningHours) IsOpen(t time.Time) bool {
+	local := t.In(London)
+	now := Clock(local.Hour(), local.Minute())
+	for _, s := range h.SessionsOn(local) {
+		if now >= s.Opens && now < s.end() {
+			return true
+		}
+	}
+	// noon, as midnight the day before may not exist when the clocks change
+	yesterday := time.Date(local.Year(), local.Month(), local.Day()-1, 12, 0, 0, 0, London)
+	for _, s := range h.SessionsOn(yesterday) {
+		if s.Overnight() && now < s.Closes {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeOpeningHours returns h with the sessions of each day ordered by opening time
+// and the overrides by date, after checking that times are within a day, the sessions of
+// a day do not overlap, only the last of them is overnight and every date is valid and
+// overridden once. It returns an error matching ErrInvalidInput if not.
+func NormalizeOpeningHours(h OpeningHours) (OpeningHours, error) {
+	var n OpeningHours
+	for day, sessions := range h.Weekly {
+		s, err := normalizeSessions(sessions)
+		if err != nil {
+			return h, fmt.Errorf("%s: %w", time.Weekday(day), err)
+		}
+		n.Weekly[day] = s
+	}
+
+	seen := make(map[string]bool, len(h.Overrides))
+	for _, o := range h.Overrides {
+		if _, err := time.Parse(DateLayout, o.Date); err != nil {
+			return h, fmt.Errorf("%w: override date %q is not YYYY-MM-DD", ErrInvalidInput, o.Date)
+		}
+		if seen[o.Date] {
+			return h, fmt.Errorf("%w: %s is overridden more than once", ErrInvalidInput, o.Date)
+		}
+		seen[o.Date] = true
+
+		s, err := normalizeSessions(o.Sessions)
+		if err != nil {
+			return h, fmt.Errorf("%s: %w", o.Date, err)
+		}
+		n.Overrides = append(n.Overrides, Override{Date: o.Date, Sessions: s})
+	}
+	sort.Slice(n.Overrides, func(i, j int) bool {
+		return n.Overrides[i].Date < n.Overrides[j].Date
+	})
+	return n, nil
+}
+
+// normalizeSessions returns a sorted copy of the sessions of one day, checking them.
+func normalizeSessions(sessions []Session) ([]Session, error) {
+	var sorted []Session
+	for _, s := range sessions {
+		if s.Opens < 0 || s.Opens >= EndOfDay || s.Closes < 0 || s.Closes > EndOfDay {
+			return nil, fmt.Errorf("%w: session %s is not within a day", ErrInvalidInput, s)
+		}
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Opens < sorted[j].Opens
+	})
+	for i := 1; i < len(sorted); i++ {
+		if prev := sorted[i-1]; sorted[i].Opens < prev.end() {
+			return nil, fmt.Errorf("%w: session %s overlaps %s", ErrInvalidInput, sorted[i], prev)
+		}
+	}
+	return sorted, nil
+}
+
+// CheckOpenNear validates the FindOpenNear limit, returning an error matching
+// ErrInvalidInput if it is not positive.
+func CheckOpenNear(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("%w: limit %d must be positive", ErrInvalidInput, limit)
+	}
+	return nil
+}
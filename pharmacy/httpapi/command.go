@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+)
+
+// Run implements the `serve [-addr :8080] [-jwt-key file]` command shared by the sql
+// examples. It serves handler until ctx is cancelled. Changes need a JWT granting
+// auth.ScopeWrite when a -jwt-key is given, otherwise anyone can make them.
+func Run(ctx context.Context, handler http.Handler, args []string, stdout io.Writer) error {
+	var (
+		flags    = flag.NewFlagSet("serve", flag.ExitOnError)
+		addr     = flags.String("addr", ":8080", "the address to listen on")
+		shutdown = flags.Duration("shutdown", 10*time.Second, "how long to wait for in flight requests on exit")
+	)
+	verifier := auth.VerifierFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	v, err := verifier()
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		handler = v.Middleware(handler)
+	} else {
+		_, _ = fmt.Fprintln(stdout, "warning: no -jwt-key so changes are not authenticated")
+	}
+
+	_, _ = fmt.Fprintf(stdout, "listening on %s\n", *addr)
+	if err := ListenAndServe(ctx, *addr, handler, *shutdown); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(stdout, "stopped")
+	return nil
+}
+
+// Serialize serves one request at a time, for a Repo that is not safe for concurrent use
+// such as one backed by a single pgx.Conn.
+func Serialize(handler http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// WithMetrics serves metrics, such as a metrics.Handler, on GET /metrics and every other
+// request with handler.
+func WithMetrics(handler, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	mux.Handle("/", handler)
+	return mux
+}
@@ -0,0 +1,569 @@
+// Package httpapi serves a pharmacy.Repo as a REST/JSON API:
+//
+//	GET    /pharmacies/{code}?as_of=2024-01-02T15:04:05Z
+//	GET    /pharmacies/{code}/history
+//	GET    /pharmacies/{code}/hours
+//	PUT    /pharmacies/{code}/hours
+//	GET    /pharmacies/{code}/open?at=2024-01-02T15:04:05Z
+//	GET    /open-pharmacies?lat=52.67&lng=-0.73&at=&limit=10
+//	GET    /pharmacies?postcode=LE&cursor=&size=50
+//	POST   /pharmacies
+//	PUT    /pharmacies/{code}
+//	DELETE /pharmacies/{code}
+//
+// Times are RFC 3339 and at defaults to now.
+//
+// Errors are returned as {"error": "message"} with 404 for an unknown code, 409 for a
+// duplicate code and 400 for a request that fails validation.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// maxBodyBytes limits the size of a POST or PUT body.
+const maxBodyBytes = 1 << 20
+
+// defaultOpenLimit is the number of pharmacies /open-pharmacies returns without a limit.
+const defaultOpenLimit = 10
+
+// weekdays are the keys of OpeningHours.Weekly, indexed by time.Weekday.
+var weekdays = [7]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// Pharmacy is the JSON representation of a pharmacy.Pharmacy. NULL columns are null.
+type Pharmacy struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	AddrLine1 *string `json:"addr_line_1"`
+	AddrLine2 *string `json:"addr_line_2"`
+	AddrLine3 *string `json:"addr_line_3"`
+	AddrLine4 *string `json:"addr_line_4"`
+	Postcode  string  `json:"postcode"`
+	Phone     *string `json:"phone"`
+	LatLng    *LatLng `json:"lat_lng"`
+}
+
+// LatLng is the JSON representation of a pharmacy.LatLng.
+type LatLng struct {
+	Lat float32 `json:"lat"`
+	Lng float32 `json:"lng"`
+}
+
+// Change is the JSON representation of a pharmacy.Change. Before is null for an insert
+// and After is null for a delete.
+type Change struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code"`
+	Operation string    `json:"operation"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+	Before    *Pharmacy `json:"before"`
+	After     *Pharmacy `json:"after"`
+}
+
+// NearbyPharmacy is the JSON representation of a pharmacy.NearbyPharmacy.
+type NearbyPharmacy struct {
+	Pharmacy
+	Distance float64 `json:"distance_meters"`
+}
+
+// Session is the JSON representation of a pharmacy.Session, with HH:MM times in London.
+type Session struct {
+	Opens  string `json:"opens"`
+	Closes string `json:"closes"`
+}
+
+// Override is the JSON representation of a pharmacy.Override. No sessions means closed
+// all day.
+type Override struct {
+	Date     string    `json:"date"`
+	Sessions []Session `json:"sessions"`
+}
+
+// OpeningHours is the JSON representation of a pharmacy.OpeningHours. Weekly is keyed by
+// the lower case name of the day, and a day without sessions is closed.
+type OpeningHours struct {
+	Weekly    map[string][]Session `json:"weekly"`
+	Overrides []Override           `json:"overrides"`
+}
+
+// OpenStatus reports if a pharmacy is open at a time.
+type OpenStatus struct {
+	Code string    `json:"code"`
+	At   time.Time `json:"at"`
+	Open bool      `json:"open"`
+}
+
+// Page is the JSON representation of a pharmacy.Page. Next is omitted on the last page.
+type Page struct {
+	Pharmacies []Pharmacy `json:"pharmacies"`
+	Next       string     `json:"next,omitempty"`
+}
+
+// FromPharmacy converts p to its JSON representation.
+func FromPharmacy(p pharmacy.Pharmacy) Pharmacy {
+	j := Pharmacy{
+		Code:      p.Code,
+		Name:      p.Name,
+		AddrLine1: pharmacy.StringPtr(p.AddrLine1),
+		AddrLine2: pharmacy.StringPtr(p.AddrLine2),
+		AddrLine3: pharmacy.StringPtr(p.AddrLine3),
+		AddrLine4: pharmacy.StringPtr(p.AddrLine4),
+		Postcode:  p.Postcode,
+		Phone:     pharmacy.StringPtr(p.Phone),
+	}
+	if p.LatLng != nil {
+		j.LatLng = &LatLng{Lat: p.LatLng.Lat, Lng: p.LatLng.Lng}
+	}
+	return j
+}
+
+// FromChange converts c to its JSON representation.
+func FromChange(c pharmacy.Change) Change {
+	j := Change{ID: c.ID, Code: c.Code, Operation: c.Operation, Actor: c.Actor, ChangedAt: c.ChangedAt}
+	if c.Before != nil {
+		before := FromPharmacy(*c.Before)
+		j.Before = &before
+	}
+	if c.After != nil {
+		after := FromPharmacy(*c.After)
+		j.After = &after
+	}
+	return j
+}
+
+// FromOpeningHours converts h to its JSON representation, with every day of the week.
+func FromOpeningHours(h pharmacy.OpeningHours) OpeningHours {
+	j := OpeningHours{Weekly: make(map[string][]Session, len(weekdays)), Overrides: make([]Override, 0, len(h.Overrides))}
+	for day, sessions := range h.Weekly {
+		j.Weekly[weekdays[day]] = fromSessions(sessions)
+	}
+	for _, o := range h.Overrides {
+		j.Overrides = append(j.Overrides, Override{Date: o.Date, Sessions: fromSessions(o.Sessions)})
+	}
+	return j
+}
+
+func fromSessions(sessions []pharmacy.Session) []Session {
+	j := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		j = append(j, Session{Opens: s.Opens.String(), Closes: s.Closes.String()})
+	}
+	return j
+}
+
+// ToOpeningHours converts j to a pharmacy.OpeningHours, returning an error matching
+// pharmacy.ErrInvalidInput for an unknown day or a time that is not HH:MM.
+func (j OpeningHours) ToOpeningHours() (pharmacy.OpeningHours, error) {
+	var h pharmacy.OpeningHours
+	for name, sessions := range j.Weekly {
+		day := -1
+		for i, weekday := range weekdays {
+			if name == weekday {
+				day = i
+			}
+		}
+		if day < 0 {
+			return h, fmt.Errorf("%w: %q is not a day of the week", pharmacy.ErrInvalidInput, name)
+		}
+		s, err := toSessions(sessions)
+		if err != nil {
+			return h, err
+		}
+		h.Weekly[day] = s
+	}
+	for _, o := range j.Overrides {
+		s, err := toSessions(o.Sessions)
+		if err != nil {
+			return h, err
+		}
+		h.Overrides = append(h.Overrides, pharmacy.Override{Date: o.Date, Sessions: s})
+	}
+	return h, nil
+}
+
+func toSessions(j []Session) ([]pharmacy.Session, error) {
+	var sessions []pharmacy.Session
+	for _, s := range j {
+		opens, err := pharmacy.ParseClockTime(s.Opens)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := pharmacy.ParseClockTime(s.Closes)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, pharmacy.Session{Opens: opens, Closes: closes})
+	}
+	return sessions, nil
+}
+
+// ToPharmacy converts j to a pharmacy.Pharmacy.
+func (j Pharmacy) ToPharmacy() pharmacy.Pharmacy {
+	p := pharmacy.Pharmacy{
+		Code:      j.Code,
+		Name:      j.Name,
+		AddrLine1: pharmacy.NullStringPtr(j.AddrLine1),
+		AddrLine2: pharmacy.NullStringPtr(j.AddrLine2),
+		AddrLine3: pharmacy.NullStringPtr(j.AddrLine3),
+		AddrLine4: pharmacy.NullStringPtr(j.AddrLine4),
+		Postcode:  j.Postcode,
+		Phone:     pharmacy.NullStringPtr(j.Phone),
+	}
+	if j.LatLng != nil {
+		p.LatLng = &pharmacy.LatLng{Lat: j.LatLng.Lat, Lng: j.LatLng.Lng}
+	}
+	return p
+}
+
+// handler holds the Repo the routes are served from.
+type handler struct {
+	repo pharmacy.Repo
+}
+
+// NewHandler returns the API routes served from repo.
+func NewHandler(repo pharmacy.Repo) http.Handler {
+	h := &handler{repo: repo}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pharmacies/{code}", h.get)
+	mux.HandleFunc("GET /pharmacies/{code}/history", h.history)
+	mux.HandleFunc("GET /pharmacies/{code}/hours", h.hours)
+	mux.HandleFunc("PUT /pharmacies/{code}/hours", h.setHours)
+	mux.HandleFunc("GET /pharmacies/{code}/open", h.isOpen)
+	mux.HandleFunc("GET /open-pharmacies", h.openNear)
+	mux.HandleFunc("GET /pharmacies", h.list)
+	mux.HandleFunc("POST /pharmacies", h.create)
+	mux.HandleFunc("PUT /pharmacies/{code}", h.update)
+	mux.HandleFunc("DELETE /pharmacies/{code}", h.delete)
+	return mux
+}
+
+// get returns the pharmacy named in the path, or as it was at the RFC 3339 time in the
+// as_of query parameter.
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	var p *pharmacy.Pharmacy
+	var err error
+	if r.URL.Query().Has("as_of") {
+		at, terr := timeParam(r, "as_of")
+		if terr != nil {
+			writeError(w, terr)
+			return
+		}
+		p, err = h.repo.FindByCodeAsOf(r.Context(), code, at)
+	} else {
+		p, err = h.repo.FindByCode(r.Context(), code)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromPharmacy(*p))
+}
+
+// history returns the changes to the pharmacy named in the path, oldest first. A code
+// that was never written has an empty history rather than a 404.
+func (h *handler) history(w http.ResponseWriter, r *http.Request) {
+	history, err := h.repo.History(r.Context(), r.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]Change, 0, len(history))
+	for _, c := range history {
+		resp = append(resp, FromChange(*c))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) hours(w http.ResponseWriter, r *http.Request) {
+	hours, err := h.repo.OpeningHours(r.Context(), r.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromOpeningHours(*hours))
+}
+
+// setHours replaces the opening hours of the pharmacy named in the path.
+func (h *handler) setHours(w http.ResponseWriter, r *http.Request) {
+	var j OpeningHours
+	if err := decodeJSON(w, r, &j, "opening hours"); err != nil {
+		writeError(w, err)
+		return
+	}
+	hours, err := j.ToOpeningHours()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	hours, err = pharmacy.NormalizeOpeningHours(hours)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.repo.SetOpeningHours(r.Context(), r.PathValue("code"), hours); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromOpeningHours(hours))
+}
+
+func (h *handler) isOpen(w http.ResponseWriter, r *http.Request) {
+	at, err := timeParam(r, "at")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	code := r.PathValue("code")
+	open, err := h.repo.IsOpen(r.Context(), code, at)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, OpenStatus{Code: code, At: at, Open: open})
+}
+
+// openNear returns the pharmacies open at a time nearest to lat and lng.
+func (h *handler) openNear(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var origin pharmacy.LatLng
+	for _, p := range []struct {
+		name string
+		dst  *float32
+	}{{"lat", &origin.Lat}, {"lng", &origin.Lng}} {
+		v, err := strconv.ParseFloat(q.Get(p.name), 32)
+		if err != nil {
+			writeError(w, badRequest("%s %q must be a number", p.name, q.Get(p.name)))
+			return
+		}
+		*p.dst = float32(v)
+	}
+	limit := defaultOpenLimit
+	if s := q.Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			writeError(w, badRequest("limit %q must be a positive number", s))
+			return
+		}
+	}
+	at, err := timeParam(r, "at")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	open, err := h.repo.FindOpenNear(r.Context(), origin, at, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]NearbyPharmacy, 0, len(open))
+	for _, n := range open {
+		resp = append(resp, NearbyPharmacy{Pharmacy: FromPharmacy(n.Pharmacy), Distance: n.Distance})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	size := 0
+	if s := q.Get("size"); s != "" {
+		var err error
+		size, err = strconv.Atoi(s)
+		if err != nil || size < 1 {
+			writeError(w, badRequest("size %q must be a positive number", s))
+			return
+		}
+	}
+
+	page, err := h.repo.FindByPostcodePage(r.Context(), q.Get("postcode"), q.Get("cursor"), size)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := Page{Pharmacies: make([]Pharmacy, 0, len(page.Pharmacies)), Next: page.Next}
+	for _, p := range page.Pharmacies {
+		resp.Pharmacies = append(resp.Pharmacies, FromPharmacy(*p))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	j, err := decode(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// normalized as the repo stores it, so the response matches a later GET
+	p, err := pharmacy.Normalize(j.ToPharmacy())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.repo.Insert(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/pharmacies/"+url.PathEscape(p.Code))
+	writeJSON(w, http.StatusCreated, FromPharmacy(p))
+}
+
+// update replaces the pharmacy named in the path. A code in the body must match it.
+func (h *handler) update(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	j, err := decode(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if j.Code != "" && j.Code != code {
+		writeError(w, badRequest("code %q does not match %q in the path", j.Code, code))
+		return
+	}
+	j.Code = code
+
+	p, err := pharmacy.Normalize(j.ToPharmacy())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.repo.Update(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromPharmacy(p))
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.repo.Delete(r.Context(), r.PathValue("code")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decode(w http.ResponseWriter, r *http.Request) (Pharmacy, error) {
+	var j Pharmacy
+	if err := decodeJSON(w, r, &j, "pharmacy"); err != nil {
+		return Pharmacy{}, err
+	}
+	return j, nil
+}
+
+// decodeJSON decodes the body of r into v, rejecting unknown fields. name says what v is
+// in the error.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, name string) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid %s JSON: %v", name, err)
+	}
+	return nil
+}
+
+// timeParam parses the RFC 3339 time in the query parameter name, which defaults to now.
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, badRequest("%s %q is not an RFC 3339 time", name, s)
+	}
+	return t, nil
+}
+
+// requestError is a problem with the request itself, reported as a 400.
+type requestError struct {
+	msg string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// status maps err to an HTTP status code.
+func status(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr), errors.Is(err, pharmacy.ErrInvalidInput), errors.Is(err, pharmacy.ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, pharmacy.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, pharmacy.ErrDuplicateCode):
+		return http.StatusConflict
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes err as a JSON error body. The details of unexpected errors are
+// logged rather than returned to the client.
+func writeError(w http.ResponseWriter, err error) {
+	code := status(err)
+	msg := err.Error()
+	if code == http.StatusInternalServerError {
+		log.Printf("httpapi: %v", err)
+		msg = http.StatusText(code)
+	}
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("httpapi: could not write response %v", err)
+	}
+}
+
+// ListenAndServe serves handler on addr until ctx is cancelled, then shuts down
+// gracefully, giving in flight requests up to shutdownTimeout to finish.
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler, shutdownTimeout time.Duration) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/httpapi"
+	"github.com/ayubmalik/go-cookbook/pharmacy/memory"
+	"github.com/ayubmalik/go-cookbook/pharmacy/metrics"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pharmacytest"
+)
+
+func TestHandler(t *testing.T) {
+	newServer := func(t *testing.T) *httptest.Server {
+		repo, err := memory.New(pharmacytest.Pharmacies()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(httpapi.NewHandler(repo))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"get", http.MethodGet, "/pharmacies/FA512", "", http.StatusOK},
+		{"get not found", http.MethodGet, "/pharmacies/XX000", "", http.StatusNotFound},
+		// open is a code like any other, not the nearby search, which would want lat and lng
+		{"get code open", http.MethodGet, "/pharmacies/open", "", http.StatusNotFound},
+		{"get as of", http.MethodGet, "/pharmacies/FA512?as_of=2999-01-01T00:00:00Z", "", http.StatusOK},
+		{"get as of before insert", http.MethodGet, "/pharmacies/FA512?as_of=2000-01-01T00:00:00Z", "", http.StatusNotFound},
+		{"get invalid as of", http.MethodGet, "/pharmacies/FA512?as_of=yesterday", "", http.StatusBadRequest},
+		{"history", http.MethodGet, "/pharmacies/FA512/history", "", http.StatusOK},
+		{"hours", http.MethodGet, "/pharmacies/FA512/hours", "", http.StatusOK},
+		{"hours not found", http.MethodGet, "/pharmacies/XX000/hours", "", http.StatusNotFound},
+		{"set hours", http.MethodPut, "/pharmacies/FA512/hours", `{"weekly":{"monday":[{"opens":"09:00","closes":"17:00"}]}}`, http.StatusOK},
+		{"set hours not found", http.MethodPut, "/pharmacies/XX000/hours", `{}`, http.StatusNotFound},
+		{"set hours unknown day", http.MethodPut, "/pharmacies/FA512/hours", `{"weekly":{"funday":[]}}`, http.StatusBadRequest},
+		{"set hours invalid time", http.MethodPut, "/pharmacies/FA512/hours", `{"weekly":{"monday":[{"opens":"9am","closes":"17:00"}]}}`, http.StatusBadRequest},
+		{"set hours overlap", http.MethodPut, "/pharmacies/FA512/hours", `{"overrides":[{"date":"2024-12-24","sessions":[{"opens":"09:00","closes":"13:00"},{"opens":"12:00","closes":"17:00"}]}]}`, http.StatusBadRequest},
+		{"is open", http.MethodGet, "/pharmacies/FA512/open", "", http.StatusOK},
+		{"is open invalid at", http.MethodGet, "/pharmacies/FA512/open?at=noon", "", http.StatusBadRequest},
+		{"is open not found", http.MethodGet, "/pharmacies/XX000/open", "", http.StatusNotFound},
+		{"open near", http.MethodGet, "/open-pharmacies?lat=52.67&lng=-0.73", "", http.StatusOK},
+		{"open near no lat", http.MethodGet, "/open-pharmacies?lng=-0.73", "", http.StatusBadRequest},
+		{"open near invalid limit", http.MethodGet, "/open-pharmacies?lat=52.67&lng=-0.73&limit=0", "", http.StatusBadRequest},
+		{"list", http.MethodGet, "/pharmacies?postcode=LE", "", http.StatusOK},
+		{"list invalid size", http.MethodGet, "/pharmacies?size=none", "", http.StatusBadRequest},
+		{"list invalid cursor", http.MethodGet, "/pharmacies?cursor=bad", "", http.StatusBadRequest},
+		{"create", http.MethodPost, "/pharmacies", `{"code":"NEW1","name":"New","postcode":"LE1 1AA"}`, http.StatusCreated},
+		{"create duplicate", http.MethodPost, "/pharmacies", `{"code":"FA512","name":"Dup"}`, http.StatusConflict},
+		{"create no code", http.MethodPost, "/pharmacies", `{"name":"No code"}`, http.StatusBadRequest},
+		{"create code too long", http.MethodPost, "/pharmacies", `{"code":"TOOLONG"}`, http.StatusBadRequest},
+		{"create unknown field", http.MethodPost, "/pharmacies", `{"code":"NEW1","colour":"red"}`, http.StatusBadRequest},
+		{"create invalid json", http.MethodPost, "/pharmacies", `{`, http.StatusBadRequest},
+		{"update", http.MethodPut, "/pharmacies/FA512", `{"name":"Updated"}`, http.StatusOK},
+		{"update not found", http.MethodPut, "/pharmacies/XX000", `{"name":"Missing"}`, http.StatusNotFound},
+		{"update code mismatch", http.MethodPut, "/pharmacies/FA512", `{"code":"FC826"}`, http.StatusBadRequest},
+		{"update invalid location", http.MethodPut, "/pharmacies/FA512", `{"lat_lng":{"lat":91,"lng":0}}`, http.StatusBadRequest},
+		{"delete", http.MethodDelete, "/pharmacies/FA512", "", http.StatusNoContent},
+		{"delete not found", http.MethodDelete, "/pharmacies/XX000", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t)
+			resp := do(t, srv, tt.method, tt.path, tt.body)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("got: %d, but want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if resp.StatusCode >= 400 {
+				var body map[string]string
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body["error"] == "" {
+					t.Errorf("got error body %v (%v), but want an error message", body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerRoundTrip(t *testing.T) {
+	repo, err := memory.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(httpapi.NewHandler(repo))
+	defer srv.Close()
+
+	body := `{"code":"NEW1","name":"New","addr_line_1":"","addr_line_2":null,"postcode":"LE1 1AA","lat_lng":{"lat":52.5,"lng":-1.1}}`
+	resp := do(t, srv, http.MethodPost, "/pharmacies", body)
+	resp.Body.Close()
+	if got, want := resp.Header.Get("Location"), "/pharmacies/NEW1"; got != want {
+		t.Errorf("got: %s, but want %s", got, want)
+	}
+
+	resp = do(t, srv, http.MethodGet, "/pharmacies/NEW1", "")
+	defer resp.Body.Close()
+	var got httpapi.Pharmacy
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("empty string is not null", func(t *testing.T) {
+		if got.AddrLine1 == nil || *got.AddrLine1 != "" {
+			t.Errorf("got: %v, but want empty string", got.AddrLine1)
+		}
+	})
+	t.Run("null stays null", func(t *testing.T) {
+		if got.AddrLine2 != nil || got.Phone != nil {
+			t.Errorf("got: %v %v, but want nil", got.AddrLine2, got.Phone)
+		}
+	})
+	t.Run("lat lng", func(t *testing.T) {
+		if got.LatLng == nil || *got.LatLng != (httpapi.LatLng{Lat: 52.5, Lng: -1.1}) {
+			t.Errorf("got: %v, but want 52.5, -1.1", got.LatLng)
+		}
+	})
+}
+
+func TestHandlerNormalizes(t *testing.T) {
+	repo, err := memory.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(httpapi.NewHandler(repo))
+	defer srv.Close()
+
+	decode := func(t *testing.T, resp *http.Response) httpapi.Pharmacy {
+		t.Helper()
+		defer resp.Body.Close()
+		var p httpapi.Pharmacy
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// the response has the postcode as stored, the same as a later GET
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   string
+	}{
+		{"create", http.MethodPost, "/pharmacies", `{"code":"NEW1","name":"New","postcode":"le15ab"}`, "LE1 5AB"},
+		{"update", http.MethodPut, "/pharmacies/NEW1", `{"name":"New","postcode":" le1 1aa "}`, "LE1 1AA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decode(t, do(t, srv, tt.method, tt.path, tt.body)); got.Postcode != tt.want {
+				t.Errorf("got: %q, but want %q", got.Postcode, tt.want)
+			}
+			if got := decode(t, do(t, srv, http.MethodGet, "/pharmacies/NEW1", "")); got.Postcode != tt.want {
+				t.Errorf("got: %q from GET, but want %q", got.Postcode, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerHistory(t *testing.T) {
+	repo, err := memory.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(httpapi.NewHandler(repo))
+	defer srv.Close()
+
+	do(t, srv, http.MethodPost, "/pharmacies", `{"code":"NEW1","name":"New"}`).Body.Close()
+	do(t, srv, http.MethodPut, "/pharmacies/NEW1", `{"name":"Renamed"}`).Body.Close()
+	do(t, srv, http.MethodDelete, "/pharmacies/NEW1", "").Body.Close()
+
+	resp := do(t, srv, http.MethodGet, "/pharmacies/NEW1/history", "")
+	defer resp.Body.Close()
+	var got []httpapi.Change
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got: %d changes, but want 3", len(got))
+	}
+	if got[0].Operation != "INSERT" || got[0].Before != nil || got[0].After.Name != "New" {
+		t.Errorf("got: %+v, but want the insert", got[0])
+	}
+	if got[1].Operation != "UPDATE" || got[1].Before.Name != "New" || got[1].After.Name != "Renamed" {
+		t.Errorf("got: %+v, but want the update", got[1])
+	}
+	if got[2].Operation != "DELETE" || got[2].Before.Name != "Renamed" || got[2].After != nil {
+		t.Errorf("got: %+v, but want the delete", got[2])
+	}
+}
+
+func TestHandlerOpeningHours(t *testing.T) {
+	repo, err := memory.New(pharmacytest.Pharmacies()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(httpapi.NewHandler(repo))
+	defer srv.Close()
+
+	// open late every day, closed on christmas day
+	body := `{"weekly":{"sunday":[{"opens":"08:00","closes":"00:30"}],"monday":[{"opens":"08:00","closes":"00:30"}],
+		"tuesday":[{"opens":"08:00","closes":"00:30"}]},"overrides":[{"date":"2024-12-25","sessions":[]}]}`
+	resp := do(t, srv, http.MethodPut, "/pharmacies/FC826/hours", body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got: %d, but want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = do(t, srv, http.MethodGet, "/pharmacies/FC826/hours", "")
+	var hours httpapi.OpeningHours
+	err = json.NewDecoder(resp.Body).Decode(&hours)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hours.Weekly["monday"]; len(got) != 1 || got[0] != (httpapi.Session{Opens: "08:00", Closes: "00:30"}) {
+		t.Errorf("got: %v, but want 08:00 to 00:30", got)
+	}
+	if got := hours.Weekly["friday"]; got == nil || len(got) != 0 {
+		t.Errorf("got: %v, but want an empty list for a closed day", got)
+	}
+
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2024-12-23T23:00:00Z", true},
+		// 00:15 GMT on Tuesday is still Monday's session
+		{"2024-12-24T00:15:00Z", true},
+		{"2024-12-24T00:45:00Z", false},
+		{"2024-12-25T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		resp := do(t, srv, http.MethodGet, "/pharmacies/FC826/open?at="+tt.at, "")
+		var status httpapi.OpenStatus
+		err := json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Open != tt.want {
+			t.Errorf("got: %v at %s, but want %v", status.Open, tt.at, tt.want)
+		}
+	}
+
+	resp = do(t, srv, http.MethodGet, "/open-pharmacies?lat=52.67&lng=-0.73&at=2024-12-23T23:00:00Z", "")
+	defer resp.Body.Close()
+	var open []httpapi.NearbyPharmacy
+	if err := json.NewDecoder(resp.Body).Decode(&open); err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].Code != "FC826" || open[0].Distance > 100 {
+		t.Errorf("got: %+v, but want FC826 nearby", open)
+	}
+}
+
+func TestHandlerPages(t *testing.T) {
+	repo, err := memory.New(pharmacytest.Pharmacies()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(httpapi.NewHandler(repo))
+	defer srv.Close()
+
+	var got []string
+	path := "/pharmacies?postcode=LE&size=2"
+	for i := 0; i < 10; i++ {
+		resp := do(t, srv, http.MethodGet, path, "")
+		var page httpapi.Page
+		err := json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page.Pharmacies {
+			got = append(got, p.Code)
+		}
+		if page.Next == "" {
+			break
+		}
+		path = "/pharmacies?postcode=LE&size=2&cursor=" + page.Next
+	}
+
+	want := []string{"FD294", "FC826", "ZZ999"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got: %v, but want %v", got, want)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	repo, err := memory.New(pharmacytest.Pharmacies()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := metrics.Handler(func() []metrics.Metric {
+		return []metrics.Metric{{Name: "up", Help: "Up.", Type: metrics.Gauge, Value: 1}}
+	})
+	srv := httptest.NewServer(httpapi.WithMetrics(httpapi.NewHandler(repo), stats))
+	t.Cleanup(srv.Close)
+
+	resp := do(t, srv, http.MethodGet, "/metrics", "")
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), "up 1\n") {
+		t.Errorf("got: %d %s, but want 200 with up 1", resp.StatusCode, b)
+	}
+
+	resp = do(t, srv, http.MethodGet, "/pharmacies/FA512", "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got: %d, but want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
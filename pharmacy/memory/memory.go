@@ -0,0 +1,465 @@
+// Package memory implements pharmacy.Repo in memory, for unit tests and demos that should
+// not need a running postgres. It passes the same pharmacytest conformance suite as the
+// postgres backends.
+package memory
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// PharmacyRepo is a pharmacy.Repo backed by a map. It is safe for concurrent use.
+type PharmacyRepo struct {
+	mu         sync.RWMutex
+	pharmacies map[string]pharmacy.Pharmacy
+	history    map[string][]*pharmacy.Change
+	lastID     int64
+	hours      map[string]pharmacy.OpeningHours
+}
+
+// UnknownActor is recorded in the history of a write whose context has no actor, where
+// postgres records the database user as there is none in memory.
+const UnknownActor = "unknown"
+
+var _ pharmacy.Repo = (*PharmacyRepo)(nil)
+
+// New returns a PharmacyRepo holding pharmacies.
+func New(pharmacies ...pharmacy.Pharmacy) (*PharmacyRepo, error) {
+	r := &PharmacyRepo{pharmacies: make(map[string]pharmacy.Pharmacy), history: make(map[string][]*pharmacy.Change),
+		hours: make(map[string]pharmacy.OpeningHours)}
+	for _, p := range pharmacies {
+		if err := r.Insert(context.Background(), p); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Import loads the valid rows of a pharmacy CSV, such as sample-pharmacies.csv, the same
+// way the postgres backends do. It is all or nothing so a duplicate code leaves the repo
+// unchanged.
+func (r *PharmacyRepo) Import(ctx context.Context, csv io.Reader) (*pharmacy.ImportStats, error) {
+	start := time.Now()
+	pr := pharmacy.NewCSVReader(csv)
+
+	var batch []pharmacy.Pharmacy
+	seen := make(map[string]bool)
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[p.Code] {
+			return nil, &pharmacy.DuplicateCodeError{Code: p.Code}
+		}
+		seen[p.Code] = true
+		batch = append(batch, *p)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range batch {
+		if _, ok := r.pharmacies[p.Code]; ok {
+			return nil, &pharmacy.DuplicateCodeError{Code: p.Code}
+		}
+	}
+	for _, p := range batch {
+		r.put(ctx, p)
+	}
+
+	return &pharmacy.ImportStats{
+		Imported: int64(len(batch)),
+		Rejected: pr.Rejected,
+		Elapsed:  time.Since(start),
+	}, nil
+}
+
+func (r *PharmacyRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.pharmacies[code]
+	if !ok {
+		return nil, pharmacy.ErrNotFound
+	}
+	c := clone(p)
+	return &c, nil
+}
+
+func (r *PharmacyRepo) FindByCodes(ctx context.Context, codes []string) (map[string]*pharmacy.Pharmacy, []string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0, len(codes))
+	for _, code := range pharmacy.UniqueCodes(codes) {
+		if p, ok := r.pharmacies[code]; ok {
+			c := clone(p)
+			pharmacies = append(pharmacies, &c)
+		}
+	}
+	found, missing := pharmacy.MatchCodes(codes, pharmacies)
+	return found, missing, nil
+}
+
+// FindByPostcode returns the pharmacies matching the partial postcode, ordered by postcode
+// and code.
+func (r *PharmacyRepo) FindByPostcode(ctx context.Context, partial string) ([]*pharmacy.Pharmacy, error) {
+	q, err := pharmacy.PostcodeQuery(partial)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(func(p pharmacy.Pharmacy) bool {
+		return q.Match(p.Postcode)
+	}), nil
+}
+
+func (r *PharmacyRepo) FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (*pharmacy.Page, error) {
+	size = pharmacy.PageSize(size)
+	q, err := pharmacy.PostcodeQuery(partial)
+	if err != nil {
+		return nil, err
+	}
+	var afterPostcode, afterCode string
+	if cursor != "" {
+		afterPostcode, afterCode, err = pharmacy.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pharmacies := r.sorted(func(p pharmacy.Pharmacy) bool {
+		if !q.Match(p.Postcode) {
+			return false
+		}
+		return cursor == "" || p.Postcode > afterPostcode || (p.Postcode == afterPostcode && p.Code > afterCode)
+	})
+
+	page := &pharmacy.Page{Pharmacies: pharmacies}
+	if len(pharmacies) > size {
+		page.Pharmacies = pharmacies[:size]
+		last := page.Pharmacies[size-1]
+		page.Next = pharmacy.EncodeCursor(last.Postcode, last.Code)
+	}
+	return page, nil
+}
+
+func (r *PharmacyRepo) FindNearest(ctx context.Context, origin pharmacy.LatLng, radiusMeters float64, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	if err := pharmacy.CheckNearest(radiusMeters, limit); err != nil {
+		return nil, err
+	}
+	box := pharmacy.NewBoundingBox(origin, radiusMeters)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nearest := make([]*pharmacy.NearbyPharmacy, 0)
+	for _, p := range r.pharmacies {
+		if p.LatLng == nil || !box.Contains(*p.LatLng) {
+			continue
+		}
+		if d := pharmacy.Distance(origin, *p.LatLng); d <= radiusMeters {
+			nearest = append(nearest, &pharmacy.NearbyPharmacy{Pharmacy: clone(p), Distance: d})
+		}
+	}
+
+	sort.Slice(nearest, func(i, j int) bool {
+		if nearest[i].Distance != nearest[j].Distance {
+			return nearest[i].Distance < nearest[j].Distance
+		}
+		return nearest[i].Code < nearest[j].Code
+	})
+	if len(nearest) > limit {
+		nearest = nearest[:limit]
+	}
+	return nearest, nil
+}
+
+// SearchByName ranks every pharmacy with pharmacy.NameScore, as there is no pg_trgm.
+func (r *PharmacyRepo) SearchByName(ctx context.Context, query string, limit int) ([]*pharmacy.ScoredPharmacy, error) {
+	if err := pharmacy.CheckSearch(query, limit); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make([]*pharmacy.ScoredPharmacy, 0)
+	for _, p := range r.pharmacies {
+		if score, ok := pharmacy.NameScore(query, p); ok {
+			found = append(found, &pharmacy.ScoredPharmacy{Pharmacy: clone(p), Score: score})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Score != found[j].Score {
+			return found[i].Score > found[j].Score
+		}
+		return found[i].Code < found[j].Code
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+func (r *PharmacyRepo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pharmacies[p.Code]; ok {
+		return &pharmacy.DuplicateCodeError{Code: p.Code}
+	}
+	r.put(ctx, p)
+	return nil
+}
+
+func (r *PharmacyRepo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pharmacies[p.Code]; !ok {
+		return pharmacy.ErrNotFound
+	}
+	r.put(ctx, p)
+	return nil
+}
+
+func (r *PharmacyRepo) Delete(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before, ok := r.pharmacies[code]
+	if !ok {
+		return pharmacy.ErrNotFound
+	}
+	delete(r.pharmacies, code)
+	delete(r.hours, code)
+	r.record(ctx, pharmacy.OpDelete, code, &before, nil)
+	return nil
+}
+
+func (r *PharmacyRepo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.put(ctx, p)
+	return nil
+}
+
+// History returns copies of the changes recorded for code, oldest first.
+func (r *PharmacyRepo) History(ctx context.Context, code string) ([]*pharmacy.Change, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]*pharmacy.Change, 0, len(r.history[code]))
+	for _, c := range r.history[code] {
+		history = append(history, cloneChange(c))
+	}
+	return history, nil
+}
+
+func (r *PharmacyRepo) FindByCodeAsOf(ctx context.Context, code string, at time.Time) (*pharmacy.Pharmacy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, err := pharmacy.AsOf(r.history[code], at)
+	if err != nil {
+		return nil, err
+	}
+	c := clone(*p)
+	return &c, nil
+}
+
+func (r *PharmacyRepo) OpeningHours(ctx context.Context, code string) (*pharmacy.OpeningHours, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.pharmacies[code]; !ok {
+		return nil, pharmacy.ErrNotFound
+	}
+	h := cloneHours(r.hours[code])
+	return &h, nil
+}
+
+func (r *PharmacyRepo) SetOpeningHours(ctx context.Context, code string, h pharmacy.OpeningHours) error {
+	h, err := pharmacy.NormalizeOpeningHours(h)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pharmacies[code]; !ok {
+		return pharmacy.ErrNotFound
+	}
+	r.hours[code] = cloneHours(h)
+	return nil
+}
+
+func (r *PharmacyRepo) IsOpen(ctx context.Context, code string, t time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.pharmacies[code]; !ok {
+		return false, pharmacy.ErrNotFound
+	}
+	return r.hours[code].IsOpen(t), nil
+}
+
+func (r *PharmacyRepo) FindOpenNear(ctx context.Context, origin pharmacy.LatLng, t time.Time, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	if err := pharmacy.CheckOpenNear(limit); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	open := make([]*pharmacy.NearbyPharmacy, 0)
+	for code, h := range r.hours {
+		p := r.pharmacies[code]
+		if p.LatLng == nil || !h.IsOpen(t) {
+			continue
+		}
+		open = append(open, &pharmacy.NearbyPharmacy{Pharmacy: clone(p), Distance: pharmacy.Distance(origin, *p.LatLng)})
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].Distance != open[j].Distance {
+			return open[i].Distance < open[j].Distance
+		}
+		return open[i].Code < open[j].Code
+	})
+	if len(open) > limit {
+		open = open[:limit]
+	}
+	return open, nil
+}
+
+// put stores p, recording an insert or an update of the pharmacy it replaces. Like the
+// postgres trigger an update that changes nothing is not recorded. The caller must hold
+// the lock.
+func (r *PharmacyRepo) put(ctx context.Context, p pharmacy.Pharmacy) {
+	before, ok := r.pharmacies[p.Code]
+	r.pharmacies[p.Code] = clone(p)
+	switch {
+	case !ok:
+		r.record(ctx, pharmacy.OpInsert, p.Code, nil, &p)
+	case !equal(before, p):
+		r.record(ctx, pharmacy.OpUpdate, p.Code, &before, &p)
+	}
+}
+
+// record appends a change to the history of code. The caller must hold the lock.
+func (r *PharmacyRepo) record(ctx context.Context, op, code string, before, after *pharmacy.Pharmacy) {
+	actor, ok := pharmacy.ActorFrom(ctx)
+	if !ok {
+		actor = UnknownActor
+	}
+	r.lastID++
+	r.history[code] = append(r.history[code], cloneChange(&pharmacy.Change{
+		ID:        r.lastID,
+		Code:      code,
+		Operation: op,
+		Actor:     actor,
+		ChangedAt: time.Now(),
+		Before:    before,
+		After:     after,
+	}))
+}
+
+// sorted returns copies of the pharmacies matching keep, ordered by postcode and code.
+// The caller must hold the lock.
+func (r *PharmacyRepo) sorted(keep func(p pharmacy.Pharmacy) bool) []*pharmacy.Pharmacy {
+	pharmacies := make([]*pharmacy.Pharmacy, 0)
+	for _, p := range r.pharmacies {
+		if keep(p) {
+			c := clone(p)
+			pharmacies = append(pharmacies, &c)
+		}
+	}
+
+	sort.Slice(pharmacies, func(i, j int) bool {
+		if pharmacies[i].Postcode != pharmacies[j].Postcode {
+			return pharmacies[i].Postcode < pharmacies[j].Postcode
+		}
+		return pharmacies[i].Code < pharmacies[j].Code
+	})
+	return pharmacies
+}
+
+// equal reports if a and b have the same columns.
+func equal(a, b pharmacy.Pharmacy) bool {
+	if (a.LatLng == nil) != (b.LatLng == nil) || (a.LatLng != nil && *a.LatLng != *b.LatLng) {
+		return false
+	}
+	a.LatLng, b.LatLng = nil, nil
+	return a == b
+}
+
+// cloneChange deep copies c.
+func cloneChange(c *pharmacy.Change) *pharmacy.Change {
+	cc := *c
+	for _, p := range []**pharmacy.Pharmacy{&cc.Before, &cc.After} {
+		if *p != nil {
+			cp := clone(**p)
+			*p = &cp
+		}
+	}
+	return &cc
+}
+
+// cloneHours deep copies h so callers can not change the stored sessions.
+func cloneHours(h pharmacy.OpeningHours) pharmacy.OpeningHours {
+	var c pharmacy.OpeningHours
+	for day, sessions := range h.Weekly {
+		c.Weekly[day] = append([]pharmacy.Session(nil), sessions...)
+	}
+	for _, o := range h.Overrides {
+		c.Overrides = append(c.Overrides, pharmacy.Override{Date: o.Date, Sessions: append([]pharmacy.Session(nil), o.Sessions...)})
+	}
+	return c
+}
+
+// clone copies p so callers can not change the stored pharmacy through LatLng.
+func clone(p pharmacy.Pharmacy) pharmacy.Pharmacy {
+	if p.LatLng != nil {
+		ll := *p.LatLng
+		p.LatLng = &ll
+	}
+	return p
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pharmacytest"
+)
+
+func TestPharmacyRepo(t *testing.T) {
+	pharmacytest.Run(t, func(t *testing.T) pharmacy.Repo {
+		r, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}, UnknownActor)
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	csv := `FA512,Lords Pharmacy,,Unit 61 The Guineas Shopping Centre,,Newmarket,CB8 8EQ,01638 428022,52.244796752929688,0.4055977463722229
+FC826,Rutland Late Night Pharmacy,,45c High Street,,Oakham,LE15 6AJ,01572 723368,not a lat,-0.7302858829498291
+FD294,Cohens Chemist,33 Hill Street,,,Hinckley,LE10 1DS,01455613100,52.541034698486328,-1.3677382469177246
+`
+
+	t.Run("seeds valid rows", func(t *testing.T) {
+		r, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := r.Import(ctx, strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Imported != 2 {
+			t.Errorf("got: %d imported, but want 2", stats.Imported)
+		}
+		if len(stats.Rejected) != 1 || stats.Rejected[0].Line != 2 {
+			t.Errorf("got: %v rejected, but want line 2", stats.Rejected)
+		}
+
+		p, err := r.FindByCode(ctx, "FD294")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.AddrLine2.Valid {
+			t.Errorf("got: %v, but want empty CSV field as NULL", p.AddrLine2)
+		}
+	})
+
+	t.Run("duplicate code imports nothing", func(t *testing.T) {
+		r, err := New(pharmacy.Pharmacy{Code: "FD294"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Import(ctx, strings.NewReader(csv))
+		if !errors.Is(err, pharmacy.ErrDuplicateCode) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrDuplicateCode)
+		}
+		if _, err := r.FindByCode(ctx, "FA512"); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	r, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := pharmacy.Pharmacy{Code: string(rune('A' + i)), Postcode: "LE1 1AA"}
+			_ = r.Upsert(ctx, p)
+			_, _ = r.FindByPostcode(ctx, "LE")
+			_ = r.Delete(ctx, p.Code)
+		}(i)
+	}
+	wg.Wait()
+}
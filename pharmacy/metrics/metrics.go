@@ -0,0 +1,60 @@
+// Package metrics serves connection pool statistics in the Prometheus text exposition
+// format, without a dependency on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Metric types.
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Metric is a single unlabelled sample. Counter names should end in _total.
+type Metric struct {
+	Name  string
+	Help  string
+	Type  string
+	Value float64
+}
+
+// Write writes metrics to w in the Prometheus text format.
+func Write(w io.Writer, metrics []Metric) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		_, _ = bw.WriteString("# HELP " + m.Name + " " + m.Help + "\n")
+		_, _ = bw.WriteString("# TYPE " + m.Name + " " + m.Type + "\n")
+		_, _ = bw.WriteString(m.Name + " " + strconv.FormatFloat(m.Value, 'g', -1, 64) + "\n")
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics returned by collect, which is called for every scrape.
+func Handler(collect func() []Metric) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = Write(w, collect())
+	})
+}
+
+// DBStats returns the metrics for a database/sql pool, named like those of the Prometheus
+// client's DBStatsCollector.
+func DBStats(s sql.DBStats) []Metric {
+	return []Metric{
+		{"go_sql_max_open_connections", "Maximum number of open connections to the database.", Gauge, float64(s.MaxOpenConnections)},
+		{"go_sql_open_connections", "The number of established connections both in use and idle.", Gauge, float64(s.OpenConnections)},
+		{"go_sql_in_use_connections", "The number of connections currently in use.", Gauge, float64(s.InUse)},
+		{"go_sql_idle_connections", "The number of idle connections.", Gauge, float64(s.Idle)},
+		{"go_sql_wait_count_total", "The total number of connections waited for.", Counter, float64(s.WaitCount)},
+		{"go_sql_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", Counter, s.WaitDuration.Seconds()},
+		{"go_sql_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", Counter, float64(s.MaxIdleClosed)},
+		{"go_sql_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", Counter, float64(s.MaxIdleTimeClosed)},
+		{"go_sql_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", Counter, float64(s.MaxLifetimeClosed)},
+	}
+}
@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	err := metrics.Write(&b, []metrics.Metric{
+		{Name: "a_total", Help: "An a.", Type: metrics.Counter, Value: 3},
+		{Name: "b_seconds", Help: "A b.", Type: metrics.Gauge, Value: 0.25},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP a_total An a.
+# TYPE a_total counter
+a_total 3
+# HELP b_seconds A b.
+# TYPE b_seconds gauge
+b_seconds 0.25
+`
+	if b.String() != want {
+		t.Errorf("got: %q, but want %q", b.String(), want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	stats := sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: 1500 * time.Millisecond}
+	h := metrics.Handler(func() []metrics.Metric { return metrics.DBStats(stats) })
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got content type: %s, but want text/plain; version=0.0.4", got)
+	}
+	for _, want := range []string{
+		"go_sql_max_open_connections 10\n",
+		"go_sql_in_use_connections 1\n",
+		"go_sql_idle_connections 2\n",
+		"go_sql_wait_count_total 4\n",
+		"go_sql_wait_duration_seconds_total 1.5\n",
+		"# TYPE go_sql_wait_count_total counter\n",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("got: %s, but want it to contain %q", rec.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("got %v, but want version 1 first", migrations)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("got version %d after %d, but want ascending", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+
+	t.Run("ordered by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0010_second.up.sql":   {Data: []byte("up 10")},
+			"0010_second.down.sql": {Data: []byte("down 10")},
+			"0002_first.up.sql":    {Data: []byte("up 2")},
+			"0002_first.down.sql":  {Data: []byte("down 2")},
+			"README.md":            {Data: []byte("ignored")},
+		}
+
+		got, err := LoadFS(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Migration{
+			{Version: 2, Name: "first", Up: "up 2", Down: "down 2"},
+			{Version: 10, Name: "second", Up: "up 10", Down: "down 10"},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got: %v, but want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("got: %v, but want %v", got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("missing down", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0001_first.up.sql": {Data: []byte("up 1")},
+		}
+
+		if _, err := LoadFS(fsys); err == nil {
+			t.Error("got nil, but want an error")
+		}
+	})
+
+}
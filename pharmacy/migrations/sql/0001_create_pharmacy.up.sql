@@ -0,0 +1,17 @@
+-- if not exists so databases created by the docker-entrypoint-initdb.d scripts can be adopted
+create table if not exists pharmacy (
+  code char(5) primary key,
+  name varchar(50),
+  addr_line_1 varchar(50),
+  addr_line_2 varchar(50),
+  addr_line_3 varchar(50),
+  addr_line_4 varchar(50),
+  postcode varchar(10),
+  phone varchar(20),
+  lat decimal,
+  lng decimal
+);
+
+create index if not exists pharmacy_lat_lng_idx on pharmacy (lat, lng);
+
+create index if not exists pharmacy_postcode_code_idx on pharmacy (postcode, code);
@@ -0,0 +1,7 @@
+drop index if exists pharmacy_search_vector_idx;
+
+drop index if exists pharmacy_search_text_trgm_idx;
+
+alter table pharmacy drop column if exists search_vector;
+
+alter table pharmacy drop column if exists search_text;
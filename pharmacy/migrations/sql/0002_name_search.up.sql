@@ -0,0 +1,17 @@
+create extension if not exists pg_trgm;
+
+-- the text searched by SearchByName, kept in step with the row by postgres
+alter table pharmacy add column if not exists search_text text generated always as (
+  coalesce(name, '') || ' ' || coalesce(addr_line_1, '') || ' ' || coalesce(addr_line_2, '') || ' ' ||
+  coalesce(addr_line_3, '') || ' ' || coalesce(addr_line_4, '')
+) stored;
+
+-- the simple configuration does not stem, so place names and brands are matched as written
+alter table pharmacy add column if not exists search_vector tsvector generated always as (
+  to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(addr_line_1, '') || ' ' ||
+    coalesce(addr_line_2, '') || ' ' || coalesce(addr_line_3, '') || ' ' || coalesce(addr_line_4, ''))
+) stored;
+
+create index if not exists pharmacy_search_text_trgm_idx on pharmacy using gin (search_text gin_trgm_ops);
+
+create index if not exists pharmacy_search_vector_idx on pharmacy using gin (search_vector);
@@ -0,0 +1,5 @@
+drop trigger if exists pharmacy_history_trigger on pharmacy;
+
+drop function if exists pharmacy_history_record();
+
+drop table if exists pharmacy_history;
@@ -0,0 +1,44 @@
+-- every change to a pharmacy, written by a trigger so imports and ad hoc SQL are recorded
+-- as well as repo calls
+create table if not exists pharmacy_history (
+  id bigserial primary key,
+  code char(5) not null,
+  operation text not null check (operation in ('INSERT', 'UPDATE', 'DELETE')),
+  actor text not null,
+  changed_at timestamptz not null default clock_timestamp(),
+  before jsonb,
+  after jsonb
+);
+
+create index if not exists pharmacy_history_code_changed_at_idx on pharmacy_history (code, changed_at, id);
+
+-- the actor is set by the repo with set_config('pharmacy.actor', ..., true) in the same
+-- transaction, otherwise it is the database user. An update that changes nothing is not
+-- recorded, so retried updates leave one entry.
+create or replace function pharmacy_history_record() returns trigger language plpgsql as $$
+begin
+  if tg_op = 'UPDATE' and old is not distinct from new then
+    return null;
+  end if;
+
+  insert into pharmacy_history (code, operation, actor, before, after) values (
+    case when tg_op = 'DELETE' then old.code else new.code end,
+    tg_op,
+    coalesce(nullif(current_setting('pharmacy.actor', true), ''), session_user),
+    case when tg_op = 'INSERT' then null else to_jsonb(old) - array['search_text', 'search_vector'] end,
+    case when tg_op = 'DELETE' then null else to_jsonb(new) - array['search_text', 'search_vector'] end
+  );
+  return null;
+end
+$$;
+
+drop trigger if exists pharmacy_history_trigger on pharmacy;
+
+create trigger pharmacy_history_trigger after insert or update or delete on pharmacy
+  for each row execute function pharmacy_history_record();
+
+-- existing pharmacies start their history here, so as of queries before now find nothing
+insert into pharmacy_history (code, operation, actor, after)
+select p.code, 'INSERT', 'migration 0003', to_jsonb(p) - array['search_text', 'search_vector']
+from pharmacy p
+where not exists (select 1 from pharmacy_history h where h.code = p.code);
@@ -0,0 +1,5 @@
+drop function if exists pharmacy_is_open(char(5), timestamptz);
+
+drop function if exists pharmacy_sessions(char(5), date);
+
+drop table if exists pharmacy_opening_hours;
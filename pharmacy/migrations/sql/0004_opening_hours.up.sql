@@ -0,0 +1,40 @@
+-- weekly sessions, with weekday 0 for Sunday as extract(dow), and overrides for a day
+-- such as a bank holiday, where a row without times means closed all day. Times are
+-- Europe/London and a session that closes at or before it opens closes the next day.
+create table if not exists pharmacy_opening_hours (
+  code char(5) not null references pharmacy (code) on delete cascade,
+  weekday smallint check (weekday between 0 and 6),
+  day date,
+  opens time,
+  closes time,
+  check ((weekday is null) <> (day is null)),
+  check ((opens is null) = (closes is null)),
+  check (day is not null or opens is not null)
+);
+
+create index if not exists pharmacy_opening_hours_code_idx on pharmacy_opening_hours (code);
+
+-- the sessions opening on a day, from its override if it has one
+create or replace function pharmacy_sessions(p_code char(5), p_day date)
+returns table (opens time, closes time) language sql stable as $$
+  select h.opens, h.closes from pharmacy_opening_hours h
+  where h.code = p_code and h.day = p_day and h.opens is not null
+  union all
+  select h.opens, h.closes from pharmacy_opening_hours h
+  where h.code = p_code and h.weekday = extract(dow from p_day)
+  and not exists (select from pharmacy_opening_hours o where o.code = p_code and o.day = p_day)
+$$;
+
+-- open in a session that opened earlier the same day in London, or in an overnight
+-- session that opened the day before, the same as pharmacy.OpeningHours.IsOpen
+create or replace function pharmacy_is_open(p_code char(5), p_at timestamptz)
+returns boolean language sql stable as $$
+  with l as (select (p_at at time zone 'Europe/London') as ts)
+  select exists (
+    select from l, pharmacy_sessions(p_code, l.ts::date) s
+    where l.ts::time >= s.opens and (s.closes <= s.opens or l.ts::time < s.closes)
+  ) or exists (
+    select from l, pharmacy_sessions(p_code, l.ts::date - 1) s
+    where s.closes <= s.opens and l.ts::time < s.closes
+  )
+$$;
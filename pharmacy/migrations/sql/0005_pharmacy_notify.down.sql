@@ -0,0 +1,3 @@
+drop trigger if exists pharmacy_notify_trigger on pharmacy;
+
+drop function if exists pharmacy_notify();
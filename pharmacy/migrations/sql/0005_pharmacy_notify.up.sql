@@ -0,0 +1,22 @@
+-- notifies listeners on the pharmacy_changes channel of every pharmacy written, as JSON
+-- such as {"code": "FA512", "operation": "UPDATE"}, when the transaction commits, without
+-- the padding of a char(5) code shorter than 5. Like the history, an update that changes
+-- nothing is not notified.
+create or replace function pharmacy_notify() returns trigger language plpgsql as $$
+begin
+  if tg_op = 'UPDATE' and old is not distinct from new then
+    return null;
+  end if;
+
+  perform pg_notify('pharmacy_changes', json_build_object(
+    'code', rtrim(case when tg_op = 'DELETE' then old.code else new.code end),
+    'operation', tg_op
+  )::text);
+  return null;
+end
+$$;
+
+drop trigger if exists pharmacy_notify_trigger on pharmacy;
+
+create trigger pharmacy_notify_trigger after insert or update or delete on pharmacy
+  for each row execute function pharmacy_notify();
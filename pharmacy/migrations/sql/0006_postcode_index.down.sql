@@ -0,0 +1,3 @@
+create index if not exists pharmacy_postcode_code_idx on pharmacy (postcode, code);
+
+drop index if exists pharmacy_coalesce_postcode_code_idx;
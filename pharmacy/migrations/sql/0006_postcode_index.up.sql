@@ -0,0 +1,5 @@
+-- FindByPostcode filters, orders and pages on coalesce(postcode, '') so a pharmacy without
+-- a postcode is found by an empty partial, which the plain (postcode, code) index can not serve
+create index if not exists pharmacy_coalesce_postcode_code_idx on pharmacy ((coalesce(postcode, '')), code);
+
+drop index if exists pharmacy_postcode_code_idx;
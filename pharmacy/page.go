@@ -0,0 +1,51 @@
+package pharmacy
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Page is one page of pharmacies ordered by postcode and code. Next is an opaque cursor
+// for the following page and is empty on the last page.
+type Page struct {
+	Pharmacies []*Pharmacy
+	Next       string
+}
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 1000
+)
+
+// ErrInvalidCursor is returned by FindByPostcodePage for a cursor it did not issue.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageSize returns size limited to MaxPageSize, or DefaultPageSize if it is not positive.
+func PageSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	if size > MaxPageSize {
+		return MaxPageSize
+	}
+	return size
+}
+
+// EncodeCursor packs the keyset of the last pharmacy on a page into an opaque string.
+func EncodeCursor(postcode, code string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(postcode + "\x00" + code))
+}
+
+// DecodeCursor unpacks a cursor made by EncodeCursor.
+func DecodeCursor(cursor string) (postcode, code string, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(b), "\x00", 2)
+	if len(parts) != 2 {
+		return "", "", ErrInvalidCursor
+	}
+	return parts[0], parts[1], nil
+}
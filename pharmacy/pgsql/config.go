@@ -0,0 +1,123 @@
+package pgsql
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SSL modes supported by both lib/pq and pgx. lib/pq has no allow or prefer.
+const (
+	SSLDisable    = "disable"
+	SSLRequire    = "require"
+	SSLVerifyCA   = "verify-ca"
+	SSLVerifyFull = "verify-full"
+)
+
+// ConnConfig is how to connect to postgres, tagged for the config package.
+type ConnConfig struct {
+	Host        string `config:"host" env:"DB_HOST" usage:"the postgres host"`
+	Port        int    `config:"port" env:"DB_PORT" usage:"the postgres port"`
+	User        string `config:"user" env:"DB_USER" usage:"the postgres user"`
+	Password    string `config:"password" env:"DB_PASSWORD" secret:"true" usage:"the postgres password"`
+	Name        string `config:"name" env:"DB_NAME" usage:"the database name"`
+	SSLMode     string `config:"sslmode" env:"DB_SSLMODE" usage:"disable, require, verify-ca or verify-full"`
+	SSLRootCert string `config:"sslrootcert" env:"DB_SSLROOTCERT" usage:"the CA certificate file to verify the server with"`
+	SSLCert     string `config:"sslcert" env:"DB_SSLCERT" usage:"the client certificate file"`
+	SSLKey      string `config:"sslkey" env:"DB_SSLKEY" usage:"the client private key file"`
+}
+
+// Validate checks c is complete and its TLS settings are consistent: verify-ca and
+// verify-full need a readable CA file, and a client certificate needs its key.
+func (c ConnConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("db.host is required"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("db.port %d is not between 1 and 65535", c.Port))
+	}
+	if c.User == "" {
+		errs = append(errs, errors.New("db.user is required"))
+	}
+	if c.Name == "" {
+		errs = append(errs, errors.New("db.name is required"))
+	}
+
+	switch c.SSLMode {
+	case SSLDisable, SSLRequire:
+	case SSLVerifyCA, SSLVerifyFull:
+		if c.SSLRootCert == "" {
+			errs = append(errs, fmt.Errorf("db.sslmode %s needs db.sslrootcert, the CA certificate file", c.SSLMode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("db.sslmode %q is not one of %s, %s, %s or %s",
+			c.SSLMode, SSLDisable, SSLRequire, SSLVerifyCA, SSLVerifyFull))
+	}
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		errs = append(errs, errors.New("db.sslcert and db.sslkey must be set together"))
+	}
+	for _, p := range c.sslFiles() {
+		if _, err := os.Stat(p[1]); err != nil {
+			errs = append(errs, fmt.Errorf("db.%s: %w", p[0], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DSN returns c as a key=value connection string, as used by lib/pq.
+func (c ConnConfig) DSN() string {
+	params := []string{
+		"host=" + quote(c.Host),
+		"port=" + strconv.Itoa(c.Port),
+		"user=" + quote(c.User),
+		"dbname=" + quote(c.Name),
+		"sslmode=" + quote(c.SSLMode),
+	}
+	if c.Password != "" {
+		params = append(params, "password="+quote(c.Password))
+	}
+	for _, p := range c.sslFiles() {
+		params = append(params, p[0]+"="+quote(p[1]))
+	}
+	return strings.Join(params, " ")
+}
+
+// URL returns c as a postgres:// URL, as used by pgx.
+func (c ConnConfig) URL() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.User(c.User),
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:   "/" + c.Name,
+	}
+	if c.Password != "" {
+		u.User = url.UserPassword(c.User, c.Password)
+	}
+	q := url.Values{"sslmode": {c.SSLMode}}
+	for _, p := range c.sslFiles() {
+		q.Set(p[0], p[1])
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// sslFiles returns the name and value of the TLS file parameters that are set.
+func (c ConnConfig) sslFiles() [][2]string {
+	var params [][2]string
+	for _, p := range [][2]string{{"sslrootcert", c.SSLRootCert}, {"sslcert", c.SSLCert}, {"sslkey", c.SSLKey}} {
+		if p[1] != "" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// quote single quotes a key=value connection string value, escaping \ and '.
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,110 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+const (
+	// SetActor names who is making changes for the pharmacy_history trigger of the
+	// 0003_pharmacy_history migration, until the end of the transaction.
+	SetActor = `select set_config('pharmacy.actor', $1, true)`
+
+	History = `select id, rtrim(code), operation, actor, changed_at, before, after
+	from pharmacy_history where code = $1
+	order by changed_at, id`
+
+	// FindByCodeAsOf selects the row after the last change at or before $2, which is
+	// NULL if that change was a delete.
+	FindByCodeAsOf = `select after from pharmacy_history
+	where code = $1 and changed_at <= $2
+	order by changed_at desc, id desc
+	limit 1`
+)
+
+// ScanChange scans a row selected by History into a Change.
+func ScanChange(row RowScanner) (*pharmacy.Change, error) {
+	c := &pharmacy.Change{}
+	var before, after []byte
+	if err := row.Scan(&c.ID, &c.Code, &c.Operation, &c.Actor, &c.ChangedAt, &before, &after); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if c.Before, err = DecodeRow(before); err != nil {
+		return nil, err
+	}
+	if c.After, err = DecodeRow(after); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AsOf returns the pharmacy in the after column read by FindByCodeAsOf, or
+// pharmacy.ErrNotFound if it was deleted.
+func AsOf(after []byte) (*pharmacy.Pharmacy, error) {
+	p, err := DecodeRow(after)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, pharmacy.ErrNotFound
+	}
+	return p, nil
+}
+
+// historyRow is a pharmacy row as recorded by to_jsonb in pharmacy_history.
+type historyRow struct {
+	Code      string   `json:"code"`
+	Name      *string  `json:"name"`
+	AddrLine1 *string  `json:"addr_line_1"`
+	AddrLine2 *string  `json:"addr_line_2"`
+	AddrLine3 *string  `json:"addr_line_3"`
+	AddrLine4 *string  `json:"addr_line_4"`
+	Postcode  *string  `json:"postcode"`
+	Phone     *string  `json:"phone"`
+	Lat       *float64 `json:"lat"`
+	Lng       *float64 `json:"lng"`
+}
+
+// DecodeRow decodes the before or after column of pharmacy_history the same way
+// ScanPharmacy reads the table, returning nil for NULL.
+func DecodeRow(b []byte) (*pharmacy.Pharmacy, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var row historyRow
+	if err := json.Unmarshal(b, &row); err != nil {
+		return nil, fmt.Errorf("decode pharmacy_history row: %w", err)
+	}
+
+	p := &pharmacy.Pharmacy{
+		// to_jsonb keeps the padding of the char(5) code that Columns trims
+		Code:      strings.TrimRight(row.Code, " "),
+		AddrLine1: pharmacy.NullStringPtr(row.AddrLine1),
+		AddrLine2: pharmacy.NullStringPtr(row.AddrLine2),
+		AddrLine3: pharmacy.NullStringPtr(row.AddrLine3),
+		AddrLine4: pharmacy.NullStringPtr(row.AddrLine4),
+		Phone:     pharmacy.NullStringPtr(row.Phone),
+	}
+	if row.Name != nil {
+		p.Name = *row.Name
+	}
+	if row.Postcode != nil {
+		p.Postcode = *row.Postcode
+	}
+	if row.Lat != nil && row.Lng != nil {
+		p.LatLng = &pharmacy.LatLng{Lat: float32(*row.Lat), Lng: float32(*row.Lng)}
+	}
+	return p, nil
+}
+
+// AsOfArg returns at for the FindByCodeAsOf query. Postgres keeps microseconds, so at is
+// truncated rather than rounded up past a change made in the same microsecond.
+func AsOfArg(at time.Time) time.Time {
+	return at.Truncate(time.Microsecond)
+}
@@ -0,0 +1,53 @@
+package pgsql_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+func TestDecodeRow(t *testing.T) {
+	t.Run("row", func(t *testing.T) {
+		b := []byte(`{"code": "FA512", "name": "Boots", "addr_line_1": "1 High St", "addr_line_2": null,
+			"postcode": "LE1 1AA", "phone": null, "lat": 52.6369, "lng": -1.1398}`)
+		got, err := pgsql.DecodeRow(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Code != "FA512" || got.Name != "Boots" || got.AddrLine1.String != "1 High St" || got.AddrLine2.Valid ||
+			got.Postcode != "LE1 1AA" || got.Phone.Valid {
+			t.Errorf("got: %+v, but want the decoded row", got)
+		}
+		if got.LatLng == nil || got.LatLng.Lat != 52.6369 || got.LatLng.Lng != -1.1398 {
+			t.Errorf("got: %+v, but want 52.6369,-1.1398", got.LatLng)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		got, err := pgsql.DecodeRow(nil)
+		if got != nil || err != nil {
+			t.Errorf("got: %+v, %v, but want nil", got, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := pgsql.DecodeRow([]byte(`{"code": 1}`)); err == nil {
+			t.Error("got no error for invalid JSON")
+		}
+	})
+}
+
+func TestAsOf(t *testing.T) {
+	if _, err := pgsql.AsOf(nil); !errors.Is(err, pharmacy.ErrNotFound) {
+		t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 999, time.UTC)
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := pgsql.AsOfArg(at); !got.Equal(want) {
+		t.Errorf("got: %s, but want %s", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package pgsql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+const (
+	// LockPharmacy stops the pharmacy being deleted while its opening hours are replaced.
+	LockPharmacy = `select code from pharmacy where code = $1 for share`
+
+	DeleteOpeningHours = `delete from pharmacy_opening_hours where code = $1`
+
+	// InsertSession takes the dates and times as text, so every driver sends them the
+	// same way.
+	InsertSession = `insert into pharmacy_opening_hours (code, weekday, day, opens, closes)
+	values ($1, $2, $3::text::date, $4::text::time, $5::text::time)`
+
+	// OpeningHours selects one row of NULLs for a pharmacy without opening hours and no
+	// rows for an unknown code.
+	OpeningHours = `select h.weekday, to_char(h.day, 'YYYY-MM-DD'), left(h.opens::text, 5),
+		left(h.closes::text, 5)
+	from pharmacy p left join pharmacy_opening_hours h on h.code = p.code
+	where p.code = $1
+	order by h.day nulls first, h.weekday, h.opens`
+
+	IsOpen = `select pharmacy_is_open(code, $2) from pharmacy where code = $1`
+
+	// FindOpenNear calculates the distance of every pharmacy with opening hours that is
+	// open at $4, as there is no radius to prefilter them by.
+	FindOpenNear = `select ` + Columns + `, d.distance
+	from pharmacy, ` + distance + `
+	where lat is not null and lng is not null
+	and exists (select from pharmacy_opening_hours h where h.code = pharmacy.code)
+	and pharmacy_is_open(code, $4)
+	order by d.distance, code
+	limit $5`
+)
+
+// SessionArgs returns the InsertSession arguments for every session of h, after
+// pharmacy.NormalizeOpeningHours, and a row without times for an override closed all day.
+func SessionArgs(code string, h pharmacy.OpeningHours) ([][]interface{}, error) {
+	h, err := pharmacy.NormalizeOpeningHours(h)
+	if err != nil {
+		return nil, err
+	}
+
+	var args [][]interface{}
+	for day, sessions := range h.Weekly {
+		for _, s := range sessions {
+			args = append(args, []interface{}{code, day, nil, s.Opens.String(), s.Closes.String()})
+		}
+	}
+	for _, o := range h.Overrides {
+		if len(o.Sessions) == 0 {
+			args = append(args, []interface{}{code, nil, o.Date, nil, nil})
+		}
+		for _, s := range o.Sessions {
+			args = append(args, []interface{}{code, nil, o.Date, s.Opens.String(), s.Closes.String()})
+		}
+	}
+	return args, nil
+}
+
+// HoursScanner collects the rows selected by OpeningHours.
+type HoursScanner struct {
+	hours pharmacy.OpeningHours
+	found bool
+}
+
+// Scan adds a row to the opening hours.
+func (s *HoursScanner) Scan(row RowScanner) error {
+	var weekday *int
+	var day, opens, closes *string
+	if err := row.Scan(&weekday, &day, &opens, &closes); err != nil {
+		return err
+	}
+	s.found = true
+
+	var sessions []pharmacy.Session
+	if opens != nil && closes != nil {
+		session, err := parseSession(*opens, *closes)
+		if err != nil {
+			return err
+		}
+		sessions = append(sessions, session)
+	}
+
+	switch {
+	case weekday != nil && *weekday >= 0 && *weekday < len(s.hours.Weekly):
+		s.hours.Weekly[*weekday] = append(s.hours.Weekly[*weekday], sessions...)
+	case day != nil:
+		// rows are ordered by day, so another session of the same day is for the last override
+		if n := len(s.hours.Overrides); n > 0 && s.hours.Overrides[n-1].Date == *day {
+			s.hours.Overrides[n-1].Sessions = append(s.hours.Overrides[n-1].Sessions, sessions...)
+		} else {
+			s.hours.Overrides = append(s.hours.Overrides, pharmacy.Override{Date: *day, Sessions: sessions})
+		}
+	}
+	return nil
+}
+
+// OpeningHours returns the opening hours scanned, or pharmacy.ErrNotFound if there were no
+// rows as the pharmacy does not exist.
+func (s *HoursScanner) OpeningHours() (*pharmacy.OpeningHours, error) {
+	if !s.found {
+		return nil, pharmacy.ErrNotFound
+	}
+	h := s.hours
+	return &h, nil
+}
+
+func parseSession(opens, closes string) (pharmacy.Session, error) {
+	o, err := pharmacy.ParseClockTime(opens)
+	if err != nil {
+		return pharmacy.Session{}, fmt.Errorf("scan opening hours: %w", err)
+	}
+	c, err := pharmacy.ParseClockTime(closes)
+	if err != nil {
+		return pharmacy.Session{}, fmt.Errorf("scan opening hours: %w", err)
+	}
+	return pharmacy.Session{Opens: o, Closes: c}, nil
+}
+
+// FindOpenNearArgs returns the FindOpenNear arguments.
+func FindOpenNearArgs(origin pharmacy.LatLng, t time.Time, limit int) ([]interface{}, error) {
+	if err := pharmacy.CheckOpenNear(limit); err != nil {
+		return nil, err
+	}
+	return []interface{}{origin.Lat, origin.Lng, pharmacy.EarthRadiusMeters, t, limit}, nil
+}
@@ -0,0 +1,117 @@
+package pgsql_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+// hoursRow is a row selected by pgsql.OpeningHours, with nil for NULL.
+type hoursRow []interface{}
+
+func (r hoursRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r) {
+		return fmt.Errorf("got %d destinations, but want %d", len(dest), len(r))
+	}
+	for i, v := range r {
+		switch d := dest[i].(type) {
+		case **int:
+			if v != nil {
+				n := v.(int)
+				*d = &n
+			}
+		case **string:
+			if v != nil {
+				s := v.(string)
+				*d = &s
+			}
+		}
+	}
+	return nil
+}
+
+func TestSessionArgs(t *testing.T) {
+	h := pharmacy.OpeningHours{Overrides: []pharmacy.Override{
+		{Date: "2024-12-26", Sessions: []pharmacy.Session{{Opens: pharmacy.Clock(10, 0), Closes: pharmacy.Clock(16, 0)}}},
+		{Date: "2024-12-25"},
+	}}
+	h.Weekly[time.Monday] = []pharmacy.Session{{Opens: pharmacy.Clock(8, 0), Closes: pharmacy.Clock(0, 30)}}
+
+	got, err := pgsql.SessionArgs("FC826", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{
+		{"FC826", 1, nil, "08:00", "00:30"},
+		{"FC826", nil, "2024-12-25", nil, nil},
+		{"FC826", nil, "2024-12-26", "10:00", "16:00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, but want %v", got, want)
+	}
+
+	h.Overrides = append(h.Overrides, pharmacy.Override{Date: "2024-12-25"})
+	if _, err := pgsql.SessionArgs("FC826", h); !errors.Is(err, pharmacy.ErrInvalidInput) {
+		t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+	}
+}
+
+func TestHoursScanner(t *testing.T) {
+	t.Run("rows", func(t *testing.T) {
+		var s pgsql.HoursScanner
+		for _, row := range []hoursRow{
+			{1, nil, "09:00", "13:00"},
+			{1, nil, "14:00", "24:00"},
+			{nil, "2024-12-25", nil, nil},
+			{nil, "2024-12-26", "10:00", "12:00"},
+			{nil, "2024-12-26", "14:00", "16:00"},
+		} {
+			if err := s.Scan(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := s.OpeningHours()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want pharmacy.OpeningHours
+		want.Weekly[time.Monday] = []pharmacy.Session{
+			{Opens: pharmacy.Clock(9, 0), Closes: pharmacy.Clock(13, 0)},
+			{Opens: pharmacy.Clock(14, 0), Closes: pharmacy.EndOfDay},
+		}
+		want.Overrides = []pharmacy.Override{
+			{Date: "2024-12-25"},
+			{Date: "2024-12-26", Sessions: []pharmacy.Session{
+				{Opens: pharmacy.Clock(10, 0), Closes: pharmacy.Clock(12, 0)},
+				{Opens: pharmacy.Clock(14, 0), Closes: pharmacy.Clock(16, 0)},
+			}},
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("got: %+v, but want %+v", *got, want)
+		}
+	})
+
+	t.Run("no opening hours", func(t *testing.T) {
+		var s pgsql.HoursScanner
+		if err := s.Scan(hoursRow{nil, nil, nil, nil}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.OpeningHours()
+		if err != nil || !reflect.DeepEqual(*got, pharmacy.OpeningHours{}) {
+			t.Errorf("got: %+v %v, but want no opening hours", got, err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var s pgsql.HoursScanner
+		if _, err := s.OpeningHours(); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+// Package pgsql holds the SQL and row mapping shared by the postgres backends, so the
+// database/sql, pgx and pgxpool examples differ only in how they talk to the driver.
+package pgsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/postcode"
+)
+
+// SQLSTATE codes translated into pharmacy errors.
+const (
+	UniqueViolation           = "23505"
+	NotNullViolation          = "23502"
+	StringDataRightTruncation = "22001"
+)
+
+// SQLSTATE codes of transient errors, worth retrying.
+const (
+	ConnectionExceptionClass = "08"
+	SerializationFailure     = "40001"
+	DeadlockDetected         = "40P01"
+	// CannotConnectNow is returned while postgres is starting up or shutting down.
+	CannotConnectNow = "57P03"
+)
+
+// duplicateKey matches the detail of a primary key violation. char(5) codes are padded.
+var duplicateKey = regexp.MustCompile(`^Key \(code\)=\((.*?) *\) already exists`)
+
+// TranslateError returns the pharmacy error for err, a driver error with SQLSTATE code and
+// the column, message and detail fields postgres reports, wrapping err. It returns err
+// unchanged for codes that have no pharmacy meaning.
+func TranslateError(err error, code, column, message, detail string) error {
+	switch code {
+	case UniqueViolation:
+		var dup string
+		if m := duplicateKey.FindStringSubmatch(detail); m != nil {
+			dup = m[1]
+		}
+		return &pharmacy.DuplicateCodeError{Code: dup, Err: err}
+	case NotNullViolation:
+		return &pharmacy.ConstraintError{Column: column, Reason: "is required", Err: err}
+	case StringDataRightTruncation:
+		// postgres does not report the column of a value that is too long
+		return &pharmacy.ConstraintError{Column: column, Reason: message, Err: err}
+	default:
+		return err
+	}
+}
+
+// Transient reports if err, a driver error with SQLSTATE code or "" if it has none, is
+// likely to succeed if retried: a connection exception, serialization failure or deadlock,
+// or a network error. Context errors are never transient as the caller has given up.
+func Transient(err error, code string) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code != "" {
+		return strings.HasPrefix(code, ConnectionExceptionClass) || code == SerializationFailure ||
+			code == DeadlockDetected || code == CannotConnectNow
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// Columns selects every pharmacy column in the order expected by ScanPharmacy.
+// name and postcode are coalesced as an empty value carries no extra meaning for them, and
+// the char(5) code is trimmed so a short code reads back as written, as it does from memory.
+const Columns = `rtrim(code), coalesce(name, ''), addr_line_1, addr_line_2, addr_line_3, addr_line_4,
+	coalesce(postcode, ''), phone, lat, lng`
+
+const (
+	FindByCode = `select ` + Columns + `
+	from pharmacy where code = $1`
+
+	// FindByCodes takes the codes as one array parameter, so any number of them is a
+	// single statement.
+	FindByCodes = `select ` + Columns + `
+	from pharmacy where code = any($1)`
+
+	// FindNearest uses a bounding box around the origin as a cheap prefilter before the
+	// haversine distance is calculated, so no PostGIS extension is needed.
+	FindNearest = `select ` + Columns + `, d.distance
+	from pharmacy, ` + distance + `
+	where lat between $4 and $5 and lng between $6 and $7 and d.distance <= $8
+	order by d.distance, code
+	limit $9`
+
+	// SearchByName adds the pg_trgm word similarity of the query to search_text, which
+	// allows for typos, to the full text rank of its words, so rows matching whole words
+	// come first. Either can match a row, and both are served by the 0002_name_search
+	// indexes.
+	SearchByName = `select ` + Columns + `, s.score
+	from pharmacy, lateral (select (word_similarity($1, search_text) +
+		ts_rank(search_vector, to_tsquery('simple', $2)))::float8) as s(score)
+	where $1 <% search_text or search_vector @@ to_tsquery('simple', $2)
+	order by s.score desc, code
+	limit $3`
+
+	Insert = `insert into pharmacy(code, name, addr_line_1, addr_line_2, addr_line_3, addr_line_4,
+		postcode, phone, lat, lng) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	Update = `update pharmacy set name = $2, addr_line_1 = $3, addr_line_2 = $4, addr_line_3 = $5,
+		addr_line_4 = $6, postcode = $7, phone = $8, lat = $9, lng = $10 where code = $1`
+
+	Delete = `delete from pharmacy where code = $1`
+
+	Upsert = Insert + `
+		on conflict (code) do update set name = excluded.name, addr_line_1 = excluded.addr_line_1,
+		addr_line_2 = excluded.addr_line_2, addr_line_3 = excluded.addr_line_3, addr_line_4 = excluded.addr_line_4,
+		postcode = excluded.postcode, phone = excluded.phone, lat = excluded.lat, lng = excluded.lng`
+)
+
+// distance is the haversine distance in metres of a pharmacy from the origin $1, $2 on a
+// sphere with radius $3, as d.distance.
+const distance = `lateral (select 2 * $3::float8 * asin(least(1, sqrt(
+		power(sin(radians(lat::float8 - $1::float8) / 2), 2) +
+		cos(radians($1::float8)) * cos(radians(lat::float8)) * power(sin(radians(lng::float8 - $2::float8) / 2), 2)
+	))) as d(distance)`
+
+// RowScanner is implemented by a single row and a row set of every driver.
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ScanPharmacy scans the Columns of row into a Pharmacy, followed by any extra columns
+// selected after them.
+func ScanPharmacy(row RowScanner, extra ...interface{}) (*pharmacy.Pharmacy, error) {
+	var lat, lng *float64
+	p := &pharmacy.Pharmacy{}
+	dest := []interface{}{&p.Code, &p.Name, &p.AddrLine1, &p.AddrLine2, &p.AddrLine3, &p.AddrLine4,
+		&p.Postcode, &p.Phone, &lat, &lng}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if lat != nil && lng != nil {
+		p.LatLng = &pharmacy.LatLng{Lat: float32(*lat), Lng: float32(*lng)}
+	}
+	return p, nil
+}
+
+// Args returns the Insert, Update and Upsert arguments for p, in pharmacy.CSVColumns order.
+// No postcode is stored as NULL, which Columns reads back as an empty string.
+func Args(p pharmacy.Pharmacy) []interface{} {
+	var lat, lng *float32
+	if p.LatLng != nil {
+		lat, lng = &p.LatLng.Lat, &p.LatLng.Lng
+	}
+	return []interface{}{p.Code, p.Name, p.AddrLine1, p.AddrLine2, p.AddrLine3, p.AddrLine4,
+		pharmacy.NullString(p.Postcode), p.Phone, lat, lng}
+}
+
+// FindByPostcode returns the query and arguments for a postcode search, see
+// pharmacy.Repo.
+func FindByPostcode(partial string) (string, []interface{}, error) {
+	where, args, err := postcodeWhere(partial)
+	if err != nil {
+		return "", nil, err
+	}
+	return `select ` + Columns + ` from pharmacy where ` + where + ` order by coalesce(postcode, ''), code`, args, nil
+}
+
+// FindByPostcodePage returns the query and arguments for a keyset paged postcode search.
+// One more row than size is selected so the caller can tell if there is a following page.
+func FindByPostcodePage(partial, cursor string, size int) (string, []interface{}, error) {
+	where, args, err := postcodeWhere(partial)
+	if err != nil {
+		return "", nil, err
+	}
+	query := `select ` + Columns + ` from pharmacy where ` + where
+
+	if cursor != "" {
+		afterPostcode, afterCode, err := pharmacy.DecodeCursor(cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		query += ` and (coalesce(postcode, ''), code) > ($2, $3)`
+		args = append(args, afterPostcode, afterCode)
+	}
+	args = append(args, size+1)
+	query += fmt.Sprintf(` order by coalesce(postcode, ''), code limit $%d`, len(args))
+	return query, args, nil
+}
+
+// postcodeWhere returns the condition matching the partial postcode, as $1. A NULL postcode
+// is searched, ordered and paged as an empty string, as Columns scans it, so an empty partial finds it.
+func postcodeWhere(partial string) (string, []interface{}, error) {
+	q, err := pharmacy.PostcodeQuery(partial)
+	if err != nil {
+		return "", nil, err
+	}
+	where := `coalesce(postcode, '') like $1 escape '\'`
+	if q.Mode == postcode.Area {
+		// the area must be followed by the district number so L does not match LE
+		where += fmt.Sprintf(` and ascii(substr(postcode, %d, 1)) between ascii('0') and ascii('9')`, len(q.Prefix)+1)
+	}
+	return where, []interface{}{q.Like()}, nil
+}
+
+// NewPage trims the extra row selected by FindByPostcodePage and sets the Next cursor.
+func NewPage(pharmacies []*pharmacy.Pharmacy, size int) *pharmacy.Page {
+	page := &pharmacy.Page{Pharmacies: pharmacies}
+	if len(pharmacies) > size {
+		page.Pharmacies = pharmacies[:size]
+		last := page.Pharmacies[size-1]
+		page.Next = pharmacy.EncodeCursor(last.Postcode, last.Code)
+	}
+	return page
+}
+
+// SearchByNameArgs returns the SearchByName arguments, matching any of the query words.
+func SearchByNameArgs(query string, limit int) ([]interface{}, error) {
+	if err := pharmacy.CheckSearch(query, limit); err != nil {
+		return nil, err
+	}
+	// terms are only letters and digits so need no quoting in a tsquery
+	terms := pharmacy.SearchTerms(query)
+	return []interface{}{strings.Join(terms, " "), strings.Join(terms, " | "), limit}, nil
+}
+
+// FindNearestArgs returns the FindNearest arguments.
+func FindNearestArgs(origin pharmacy.LatLng, radiusMeters float64, limit int) ([]interface{}, error) {
+	if err := pharmacy.CheckNearest(radiusMeters, limit); err != nil {
+		return nil, err
+	}
+
+	box := pharmacy.NewBoundingBox(origin, radiusMeters)
+	return []interface{}{
+		origin.Lat, origin.Lng, pharmacy.EarthRadiusMeters,
+		box.MinLat, box.MaxLat, box.MinLng, box.MaxLng,
+		radiusMeters, limit,
+	}, nil
+}
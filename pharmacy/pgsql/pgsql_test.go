@@ -0,0 +1,169 @@
+package pgsql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+// driverError stands in for a *pq.Error or *pgconn.PgError.
+type driverError struct{}
+
+func (driverError) Error() string { return "driver error" }
+
+func TestTranslateError(t *testing.T) {
+	driverErr := driverError{}
+
+	t.Run("unique violation", func(t *testing.T) {
+		err := pgsql.TranslateError(driverErr, pgsql.UniqueViolation, "", "duplicate key",
+			"Key (code)=(FA512) already exists.")
+		var dupErr *pharmacy.DuplicateCodeError
+		if !errors.As(err, &dupErr) || dupErr.Code != "FA512" {
+			t.Errorf("got: %#v, but want a DuplicateCodeError for FA512", err)
+		}
+		if !errors.Is(err, pharmacy.ErrDuplicateCode) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrDuplicateCode)
+		}
+	})
+
+	t.Run("unique violation padded code", func(t *testing.T) {
+		err := pgsql.TranslateError(driverErr, pgsql.UniqueViolation, "", "duplicate key",
+			"Key (code)=(AB   ) already exists.")
+		var dupErr *pharmacy.DuplicateCodeError
+		if !errors.As(err, &dupErr) || dupErr.Code != "AB" {
+			t.Errorf("got: %#v, but want a DuplicateCodeError for AB", err)
+		}
+	})
+
+	t.Run("not null violation", func(t *testing.T) {
+		err := pgsql.TranslateError(driverErr, pgsql.NotNullViolation, "code", "null value", "")
+		var conErr *pharmacy.ConstraintError
+		if !errors.As(err, &conErr) || conErr.Column != "code" {
+			t.Errorf("got: %#v, but want a ConstraintError for code", err)
+		}
+		if !errors.Is(err, pharmacy.ErrInvalidInput) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+		}
+	})
+
+	t.Run("string data right truncation", func(t *testing.T) {
+		msg := "value too long for type character varying(50)"
+		err := pgsql.TranslateError(driverErr, pgsql.StringDataRightTruncation, "", msg, "")
+		if !errors.Is(err, pharmacy.ErrInvalidInput) || err.Error() != msg {
+			t.Errorf("got: %v, but want %v: %s", err, pharmacy.ErrInvalidInput, msg)
+		}
+	})
+
+	t.Run("driver error kept", func(t *testing.T) {
+		err := pgsql.TranslateError(driverErr, pgsql.UniqueViolation, "", "", "")
+		if !errors.As(err, &driverError{}) {
+			t.Errorf("got: %#v, but want it to wrap the driver error", err)
+		}
+	})
+
+	t.Run("other codes unchanged", func(t *testing.T) {
+		err := pgsql.TranslateError(driverErr, "40001", "", "", "")
+		if err != error(driverErr) {
+			t.Errorf("got: %v, but want %v", err, driverErr)
+		}
+	})
+}
+
+func TestFindByPostcode(t *testing.T) {
+	t.Run("district", func(t *testing.T) {
+		query, args, err := pgsql.FindByPostcode("le1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(args) != 1 || args[0] != "LE1 %" {
+			t.Errorf("got args: %v, but want [LE1 %%]", args)
+		}
+		if strings.Contains(query, "ascii") {
+			t.Errorf("got: %s, but want no area check for a district", query)
+		}
+	})
+
+	t.Run("area", func(t *testing.T) {
+		query, _, err := pgsql.FindByPostcode("LE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(query, "substr(postcode, 3, 1)") {
+			t.Errorf("got: %s, but want the third character checked for a digit", query)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, _, err := pgsql.FindByPostcode("LE%")
+		if !errors.Is(err, pharmacy.ErrInvalidInput) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+		}
+	})
+
+	t.Run("page", func(t *testing.T) {
+		cursor := pharmacy.EncodeCursor("LE1 5AB", "FA512")
+		query, args, err := pgsql.FindByPostcodePage("LE1", cursor, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(args) != 4 || args[1] != "LE1 5AB" || args[3] != 11 {
+			t.Errorf("got args: %v, but want [LE1 %% LE1 5AB FA512 11]", args)
+		}
+		if !strings.HasSuffix(query, "limit $4") {
+			t.Errorf("got: %s, but want limit $4", query)
+		}
+	})
+}
+
+func TestSearchByNameArgs(t *testing.T) {
+	args, err := pgsql.SearchByNameArgs("Lloyds, Sainsbury's", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 3 || args[0] != "lloyds sainsbury s" || args[1] != "lloyds | sainsbury | s" || args[2] != 5 {
+		t.Errorf("got args: %v, but want [lloyds sainsbury s, lloyds | sainsbury | s, 5]", args)
+	}
+
+	// punctuation alone would be an empty tsquery
+	if _, err := pgsql.SearchByNameArgs("'&!", 5); !errors.Is(err, pharmacy.ErrInvalidInput) {
+		t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+		want bool
+	}{
+		{"connection failure", errors.New("pq"), "08006", true},
+		{"serialization failure", errors.New("pq"), pgsql.SerializationFailure, true},
+		{"deadlock", errors.New("pq"), pgsql.DeadlockDetected, true},
+		{"starting up", errors.New("pq"), pgsql.CannotConnectNow, true},
+		{"unique violation", errors.New("pq"), pgsql.UniqueViolation, false},
+		{"query canceled", errors.New("pq"), "57014", false},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, "", true},
+		{"bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), "", true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, "", true},
+		{"deadline", context.DeadlineExceeded, "", false},
+		{"cancelled", fmt.Errorf("dial: %w", context.Canceled), "", false},
+		{"not found", pharmacy.ErrNotFound, "", false},
+		{"nil", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pgsql.Transient(tt.err, tt.code); got != tt.want {
+				t.Errorf("got: %v, but want %v", got, tt.want)
+			}
+		})
+	}
+}
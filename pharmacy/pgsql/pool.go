@@ -0,0 +1,57 @@
+package pgsql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PoolSettings tunes a connection pool. A zero value keeps the driver default.
+type PoolSettings struct {
+	MaxConns          int           `config:"max_conns" env:"DB_MAX_CONNS" usage:"the most open connections"`
+	MinConns          int           `config:"min_conns" env:"DB_MIN_CONNS" usage:"the fewest connections to keep open"`
+	MaxConnLifetime   time.Duration `config:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" usage:"how long a connection is reused for"`
+	MaxConnIdleTime   time.Duration `config:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" usage:"how long a connection may be idle"`
+	HealthCheckPeriod time.Duration `config:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" usage:"how often idle connections are checked"`
+}
+
+// Validate checks the settings are not negative and MinConns is not more than MaxConns.
+func (s PoolSettings) Validate() error {
+	if s.MaxConns < 0 || s.MinConns < 0 || s.MaxConnLifetime < 0 || s.MaxConnIdleTime < 0 || s.HealthCheckPeriod < 0 {
+		return fmt.Errorf("pool settings %+v must not be negative", s)
+	}
+	if s.MaxConns > 0 && s.MinConns > s.MaxConns {
+		return fmt.Errorf("pool min conns %d is more than max conns %d", s.MinConns, s.MaxConns)
+	}
+	return nil
+}
+
+// ValidateSQL checks the settings as Validate does and that database/sql can apply them.
+// It keeps no minimum number of connections, so MinConns is rejected rather than ignored.
+func (s PoolSettings) ValidateSQL() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.MinConns > 0 {
+		return fmt.Errorf("pool min conns %d is not supported by database/sql", s.MinConns)
+	}
+	return nil
+}
+
+// Configure applies s to db, returning the error of ValidateSQL if it can not. database/sql
+// does not health check idle connections, so HealthCheckPeriod is ignored.
+func (s PoolSettings) Configure(db *sql.DB) error {
+	if err := s.ValidateSQL(); err != nil {
+		return err
+	}
+	if s.MaxConns > 0 {
+		db.SetMaxOpenConns(s.MaxConns)
+	}
+	if s.MaxConnLifetime > 0 {
+		db.SetConnMaxLifetime(s.MaxConnLifetime)
+	}
+	if s.MaxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(s.MaxConnIdleTime)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package pgsql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+func TestPoolSettingsValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for _, s := range []pgsql.PoolSettings{
+			{},
+			{MaxConns: 20, MinConns: 2, MaxConnLifetime: time.Hour, MaxConnIdleTime: 30 * time.Minute, HealthCheckPeriod: time.Minute},
+			{MinConns: 5},
+		} {
+			if err := s.Validate(); err != nil {
+				t.Errorf("got: %v for %+v, but want no error", err, s)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, s := range map[string]pgsql.PoolSettings{
+			"negative max conns":    {MaxConns: -1},
+			"negative min conns":    {MinConns: -1},
+			"negative lifetime":     {MaxConnLifetime: -time.Second},
+			"negative idle time":    {MaxConnIdleTime: -time.Second},
+			"negative health check": {HealthCheckPeriod: -time.Second},
+			"min more than max":     {MaxConns: 2, MinConns: 5},
+		} {
+			t.Run(name, func(t *testing.T) {
+				if err := s.Validate(); err == nil {
+					t.Errorf("got no error for %+v", s)
+				}
+			})
+		}
+	})
+}
+
+func TestPoolSettingsValidateSQL(t *testing.T) {
+	valid := pgsql.PoolSettings{MaxConns: 20, MaxConnLifetime: time.Hour, MaxConnIdleTime: 30 * time.Minute, HealthCheckPeriod: time.Minute}
+	if err := valid.ValidateSQL(); err != nil {
+		t.Errorf("got: %v for %+v, but want no error", err, valid)
+	}
+
+	for name, s := range map[string]pgsql.PoolSettings{
+		"min conns":          {MinConns: 2},
+		"negative max conns": {MaxConns: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := s.ValidateSQL(); err == nil {
+				t.Errorf("got no error for %+v", s)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+const (
+	// Listen subscribes a connection to the pharmacy_changes channel notified by the
+	// trigger of the 0005_pharmacy_notify migration.
+	Listen = `listen pharmacy_changes`
+
+	// TerminateListeners drops every other connection listening for pharmacy changes, for
+	// tests that a watch reconnects. The query of an idle connection is its last.
+	TerminateListeners = `select pg_terminate_backend(pid) from pg_stat_activity
+	where query = 'listen pharmacy_changes' and pid <> pg_backend_pid()`
+)
+
+// ParseChangeEvent decodes the payload of a pharmacy_changes notification.
+func ParseChangeEvent(payload string) (pharmacy.ChangeEvent, error) {
+	var e struct {
+		Code      string `json:"code"`
+		Operation string `json:"operation"`
+	}
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		return pharmacy.ChangeEvent{}, fmt.Errorf("decode pharmacy_changes payload %q: %w", payload, err)
+	}
+	switch e.Operation {
+	case pharmacy.OpInsert, pharmacy.OpUpdate, pharmacy.OpDelete:
+	default:
+		return pharmacy.ChangeEvent{}, fmt.Errorf("decode pharmacy_changes payload %q: unknown operation", payload)
+	}
+	if e.Code == "" {
+		return pharmacy.ChangeEvent{}, fmt.Errorf("decode pharmacy_changes payload %q: no code", payload)
+	}
+	return pharmacy.ChangeEvent{Code: e.Code, Operation: e.Operation}, nil
+}
@@ -0,0 +1,29 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+func TestParseChangeEvent(t *testing.T) {
+	got, err := pgsql.ParseChangeEvent(`{"code" : "FA512", "operation" : "UPDATE"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (pharmacy.ChangeEvent{Code: "FA512", Operation: pharmacy.OpUpdate}); got != want {
+		t.Errorf("got: %+v, but want %+v", got, want)
+	}
+
+	for _, payload := range []string{
+		`FA512`,
+		`{"code": "FA512", "operation": "TRUNCATE"}`,
+		`{"operation": "DELETE"}`,
+		`{"code": "", "operation": "DELETE"}`,
+	} {
+		if _, err := pgsql.ParseChangeEvent(payload); err == nil {
+			t.Errorf("got no error for %s", payload)
+		}
+	}
+}
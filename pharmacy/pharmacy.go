@@ -0,0 +1,202 @@
+// Package pharmacy is the domain model shared by the sql cookbook examples: an NHS
+// pharmacy, taken from public UK data, and the Repo interface each storage backend
+// implements.
+package pharmacy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/postcode"
+)
+
+// Pharmacy maps a row of the pharmacy table. Address lines and phone are nullable
+// so an empty string can be told apart from NULL, and LatLng is nil when the
+// pharmacy has no coordinates.
+type Pharmacy struct {
+	Code      string
+	Name      string
+	AddrLine1 sql.NullString
+	AddrLine2 sql.NullString
+	AddrLine3 sql.NullString
+	AddrLine4 sql.NullString
+	Postcode  string
+	Phone     sql.NullString
+	LatLng    *LatLng
+}
+
+type LatLng struct {
+	Lat float32
+	Lng float32
+}
+
+// NearbyPharmacy is a Pharmacy found by FindNearest along with its great-circle distance
+// in metres from the search origin.
+type NearbyPharmacy struct {
+	Pharmacy
+	Distance float64
+}
+
+// Repo stores pharmacies. Every backend must behave the same way, which is checked by
+// the pharmacytest conformance suite. In particular FindByCode, Update and Delete return
+// ErrNotFound for an unknown code, Insert returns a *DuplicateCodeError for a known one,
+// and Insert, Update and Upsert return a *ConstraintError for a pharmacy that fails
+// Normalize or a constraint of the table. Postcodes are stored in canonical form.
+type Repo interface {
+	FindByCode(ctx context.Context, code string) (*Pharmacy, error)
+	// FindByCodes looks up many codes in one round trip, returning the pharmacies found
+	// keyed by code and the codes that were not, once each in the order given.
+	FindByCodes(ctx context.Context, codes []string) (map[string]*Pharmacy, []string, error)
+	// FindByPostcode returns the pharmacies in an area (LE), district (LE1) or sector
+	// (LE1 5), or at a postcode (LE1 5AB), ignoring case and spacing. An empty partial
+	// returns every pharmacy.
+	FindByPostcode(ctx context.Context, partial string) ([]*Pharmacy, error)
+	FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (*Page, error)
+	FindNearest(ctx context.Context, origin LatLng, radiusMeters float64, limit int) ([]*NearbyPharmacy, error)
+	// SearchByName returns up to limit pharmacies whose name or address lines match the
+	// words of query, allowing for typos, best match first.
+	SearchByName(ctx context.Context, query string, limit int) ([]*ScoredPharmacy, error)
+	Insert(ctx context.Context, p Pharmacy) error
+	Update(ctx context.Context, p Pharmacy) error
+	Delete(ctx context.Context, code string) error
+	Upsert(ctx context.Context, p Pharmacy) error
+	// History returns every change to the pharmacy with code, oldest first, including
+	// those before it was deleted. Writes record the actor in their context, see
+	// WithActor, otherwise a default of the backend, the database user for postgres.
+	History(ctx context.Context, code string) ([]*Change, error)
+	// FindByCodeAsOf returns the pharmacy with code as it was at a time, from its
+	// history, or ErrNotFound if it did not exist then.
+	FindByCodeAsOf(ctx context.Context, code string, at time.Time) (*Pharmacy, error)
+	// OpeningHours returns the opening hours of the pharmacy with code, normalized by
+	// NormalizeOpeningHours. They are empty, never open, until set.
+	OpeningHours(ctx context.Context, code string) (*OpeningHours, error)
+	// SetOpeningHours replaces the opening hours of the pharmacy with code after
+	// NormalizeOpeningHours. Deleting a pharmacy deletes its opening hours.
+	SetOpeningHours(ctx context.Context, code string, h OpeningHours) error
+	// IsOpen reports if the pharmacy with code is open at t, see OpeningHours.IsOpen.
+	IsOpen(ctx context.Context, code string, t time.Time) (bool, error)
+	// FindOpenNear returns up to limit pharmacies with coordinates that are open at t,
+	// nearest to origin first.
+	FindOpenNear(ctx context.Context, origin LatLng, t time.Time, limit int) ([]*NearbyPharmacy, error)
+}
+
+// UniqueCodes returns codes without repeats, in the order given.
+func UniqueCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	unique := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	return unique
+}
+
+// MatchCodes returns pharmacies keyed by the code asked for, and the codes with no
+// pharmacy, for FindByCodes.
+func MatchCodes(codes []string, pharmacies []*Pharmacy) (map[string]*Pharmacy, []string) {
+	byCode := make(map[string]*Pharmacy, len(pharmacies))
+	for _, p := range pharmacies {
+		byCode[p.Code] = p
+	}
+
+	found := make(map[string]*Pharmacy, len(pharmacies))
+	missing := make([]string, 0)
+	for _, code := range UniqueCodes(codes) {
+		if p, ok := byCode[code]; ok {
+			found[code] = p
+		} else {
+			missing = append(missing, code)
+		}
+	}
+	return found, missing
+}
+
+// NullString returns s as a sql.NullString, treating an empty string as NULL.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// NullStringPtr returns s as a sql.NullString, treating nil as NULL. Unlike NullString an
+// empty string is kept, for encodings such as JSON that can tell the two apart.
+func NullStringPtr(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// StringPtr returns s as a *string which is nil for NULL.
+func StringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// Normalize returns p with its postcode in canonical form, see the postcode package, after
+// checking it with Validate. It returns a *ConstraintError for a postcode that is not a UK
+// postcode. An empty postcode is allowed.
+func Normalize(p Pharmacy) (Pharmacy, error) {
+	if p.Postcode != "" {
+		pc, err := postcode.Normalize(p.Postcode)
+		if err != nil {
+			return p, &ConstraintError{Column: "postcode", Reason: fmt.Sprintf("%q is not a UK postcode", p.Postcode), Err: err}
+		}
+		p.Postcode = pc
+	}
+	return p, Validate(p)
+}
+
+// PostcodeQuery parses the partial postcode of a FindByPostcode search, returning an error
+// matching ErrInvalidInput if it is not an area, district, sector or full postcode.
+func PostcodeQuery(partial string) (postcode.Query, error) {
+	q, err := postcode.ParseQuery(partial)
+	if err != nil {
+		return q, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return q, nil
+}
+
+// Validate checks p fits the pharmacy table: a code is required, every column must be
+// within its maximum length and coordinates must be on the globe. It returns a
+// *ConstraintError for the first column that does not.
+func Validate(p Pharmacy) error {
+	if p.Code == "" {
+		return &ConstraintError{Column: "code", Reason: "is required"}
+	}
+
+	fields := []struct {
+		name   string
+		value  string
+		maxLen int
+	}{
+		{"code", p.Code, 5},
+		{"name", p.Name, 50},
+		{"addr_line_1", p.AddrLine1.String, 50},
+		{"addr_line_2", p.AddrLine2.String, 50},
+		{"addr_line_3", p.AddrLine3.String, 50},
+		{"addr_line_4", p.AddrLine4.String, 50},
+		{"postcode", p.Postcode, 10},
+		{"phone", p.Phone.String, 20},
+	}
+	for _, f := range fields {
+		if utf8.RuneCountInString(f.value) > f.maxLen {
+			return &ConstraintError{Column: f.name, Reason: fmt.Sprintf("is longer than %d characters", f.maxLen)}
+		}
+	}
+
+	if ll := p.LatLng; ll != nil {
+		if ll.Lat < -90 || ll.Lat > 90 {
+			return &ConstraintError{Column: "lat", Reason: fmt.Sprintf("%v is not a valid latitude", ll.Lat)}
+		}
+		if ll.Lng < -180 || ll.Lng > 180 {
+			return &ConstraintError{Column: "lng", Reason: fmt.Sprintf("%v is not a valid longitude", ll.Lng)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package pharmacytest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// BenchmarkFindByCodes compares looking up n codes with FindByCode in a loop against one
+// FindByCodes call. repo must be empty, and n pharmacies are inserted before the timer
+// starts.
+func BenchmarkFindByCodes(b *testing.B, repo pharmacy.Repo, n int) {
+	ctx := context.Background()
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("B%04d", i)
+		p := pharmacy.Pharmacy{Code: codes[i], Name: "Benchmark Pharmacy", Postcode: "LE1 1AA"}
+		if err := repo.Insert(ctx, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("FindByCode loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, code := range codes {
+				if _, err := repo.FindByCode(ctx, code); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("FindByCodes", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			found, _, err := repo.FindByCodes(ctx, codes)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(found) != n {
+				b.Fatalf("got %d pharmacies, but want %d", len(found), n)
+			}
+		}
+	})
+}
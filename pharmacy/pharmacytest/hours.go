@@ -0,0 +1,263 @@
+package pharmacytest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// OpeningHours returns the opening hours of the Pharmacies fixtures, keyed by code: lunch
+// breaks, a late night pharmacy open past midnight with Christmas overrides, and the one
+// without coordinates open 24 hours.
+func OpeningHours() map[string]pharmacy.OpeningHours {
+	session := func(opens, closes pharmacy.ClockTime) pharmacy.Session {
+		return pharmacy.Session{Opens: opens, Closes: closes}
+	}
+	weekdays := func(sessions ...pharmacy.Session) (week [7][]pharmacy.Session) {
+		for day := time.Monday; day <= time.Friday; day++ {
+			week[day] = sessions
+		}
+		return week
+	}
+	everyDay := func(sessions ...pharmacy.Session) (week [7][]pharmacy.Session) {
+		for day := range week {
+			week[day] = sessions
+		}
+		return week
+	}
+
+	lords := weekdays(session(pharmacy.Clock(9, 0), pharmacy.Clock(13, 0)), session(pharmacy.Clock(14, 0), pharmacy.Clock(17, 30)))
+	lords[time.Saturday] = []pharmacy.Session{session(pharmacy.Clock(9, 0), pharmacy.Clock(13, 0))}
+
+	return map[string]pharmacy.OpeningHours{
+		"FA512": {
+			Weekly:    lords,
+			Overrides: []pharmacy.Override{{Date: "2024-12-25"}},
+		},
+		"FC826": {
+			Weekly: everyDay(session(pharmacy.Clock(8, 0), pharmacy.Clock(0, 30))),
+			Overrides: []pharmacy.Override{
+				{Date: "2024-12-25"},
+				{Date: "2024-12-26", Sessions: []pharmacy.Session{session(pharmacy.Clock(10, 0), pharmacy.Clock(16, 0))}},
+			},
+		},
+		"FD294": {
+			Weekly: weekdays(session(pharmacy.Clock(8, 30), pharmacy.Clock(18, 0))),
+		},
+		"ZZ999": {
+			Weekly: everyDay(session(pharmacy.Clock(0, 0), pharmacy.EndOfDay)),
+		},
+	}
+}
+
+// runHours runs the opening hours tests of Run.
+func runHours(t *testing.T, newRepo, seeded func(t *testing.T) pharmacy.Repo) {
+	ctx := context.Background()
+
+	withHours := func(t *testing.T) pharmacy.Repo {
+		repo := seeded(t)
+		for code, h := range OpeningHours() {
+			if err := repo.SetOpeningHours(ctx, code, h); err != nil {
+				t.Fatalf("could not set the opening hours of %s: %v", code, err)
+			}
+		}
+		return repo
+	}
+	london := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, pharmacy.London)
+	}
+
+	t.Run("opening hours", func(t *testing.T) {
+		repo := withHours(t)
+		for code, h := range OpeningHours() {
+			want, err := pharmacy.NormalizeOpeningHours(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := repo.OpeningHours(ctx, code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("got: %+v, but want %+v", *got, want)
+			}
+		}
+	})
+
+	t.Run("opening hours replaced", func(t *testing.T) {
+		repo := withHours(t)
+		// unordered sessions come back ordered
+		h := pharmacy.OpeningHours{Overrides: []pharmacy.Override{{Date: "2025-01-01", Sessions: []pharmacy.Session{
+			{Opens: pharmacy.Clock(14, 0), Closes: pharmacy.Clock(16, 0)},
+			{Opens: pharmacy.Clock(10, 0), Closes: pharmacy.Clock(12, 0)},
+		}}}}
+		if err := repo.SetOpeningHours(ctx, "FC826", h); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.OpeningHours(ctx, "FC826")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := pharmacy.NormalizeOpeningHours(h)
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("got: %+v, but want %+v", *got, want)
+		}
+
+		if err := repo.SetOpeningHours(ctx, "FC826", pharmacy.OpeningHours{}); err != nil {
+			t.Fatal(err)
+		}
+		got, err = repo.OpeningHours(ctx, "FC826")
+		if err != nil || !reflect.DeepEqual(*got, pharmacy.OpeningHours{}) {
+			t.Errorf("got: %+v %v, but want no opening hours", got, err)
+		}
+	})
+
+	t.Run("opening hours deleted with the pharmacy", func(t *testing.T) {
+		repo := withHours(t)
+		p := Pharmacies()[1]
+		if err := repo.Delete(ctx, p.Code); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.OpeningHours(ctx, p.Code)
+		if err != nil || !reflect.DeepEqual(*got, pharmacy.OpeningHours{}) {
+			t.Errorf("got: %+v %v, but want no opening hours", got, err)
+		}
+	})
+
+	t.Run("opening hours not found", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.OpeningHours(ctx, "XX000"); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+		if err := repo.SetOpeningHours(ctx, "XX000", pharmacy.OpeningHours{}); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+		if _, err := repo.IsOpen(ctx, "XX000", time.Now()); !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	t.Run("opening hours invalid", func(t *testing.T) {
+		repo := seeded(t)
+		tests := []struct {
+			name string
+			h    pharmacy.OpeningHours
+		}{
+			{"overlap", pharmacy.OpeningHours{Weekly: [7][]pharmacy.Session{time.Monday: {
+				{Opens: pharmacy.Clock(9, 0), Closes: pharmacy.Clock(13, 0)},
+				{Opens: pharmacy.Clock(12, 0), Closes: pharmacy.Clock(17, 0)},
+			}}}},
+			{"overnight before another", pharmacy.OpeningHours{Weekly: [7][]pharmacy.Session{time.Monday: {
+				{Opens: pharmacy.Clock(9, 0), Closes: pharmacy.Clock(1, 0)},
+				{Opens: pharmacy.Clock(20, 0), Closes: pharmacy.Clock(22, 0)},
+			}}}},
+			{"opens at 24:00", pharmacy.OpeningHours{Weekly: [7][]pharmacy.Session{time.Monday: {
+				{Opens: pharmacy.EndOfDay, Closes: pharmacy.Clock(1, 0)},
+			}}}},
+			{"invalid date", pharmacy.OpeningHours{Overrides: []pharmacy.Override{{Date: "25/12/2024"}}}},
+			{"date twice", pharmacy.OpeningHours{Overrides: []pharmacy.Override{{Date: "2024-12-25"}, {Date: "2024-12-25"}}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := repo.SetOpeningHours(ctx, "FA512", tt.h); !errors.Is(err, pharmacy.ErrInvalidInput) {
+					t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+				}
+			})
+		}
+	})
+
+	t.Run("is open", func(t *testing.T) {
+		repo := withHours(t)
+		tests := []struct {
+			name string
+			code string
+			at   time.Time
+			want bool
+		}{
+			{"late night", "FC826", london(2024, 12, 23, 23, 0), true},
+			{"after midnight", "FC826", london(2024, 12, 24, 0, 15), true},
+			{"closed after midnight", "FC826", london(2024, 12, 24, 0, 30), false},
+			{"before opening", "FC826", london(2024, 12, 24, 7, 59), false},
+			{"opening", "FC826", london(2024, 12, 24, 8, 0), true},
+			{"christmas eve into christmas day", "FC826", london(2024, 12, 25, 0, 15), true},
+			{"christmas day", "FC826", london(2024, 12, 25, 12, 0), false},
+			{"no late night on christmas day", "FC826", london(2024, 12, 26, 0, 15), false},
+			{"boxing day", "FC826", london(2024, 12, 26, 12, 0), true},
+			{"boxing day closed", "FC826", london(2024, 12, 26, 17, 0), false},
+			{"morning", "FA512", london(2024, 12, 23, 12, 59), true},
+			{"lunch", "FA512", london(2024, 12, 23, 13, 0), false},
+			{"afternoon", "FA512", london(2024, 12, 23, 14, 0), true},
+			{"saturday afternoon", "FA512", london(2024, 12, 21, 15, 0), false},
+			{"sunday", "FA512", london(2024, 12, 22, 10, 0), false},
+			{"winter is GMT", "FA512", time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), true},
+			{"summer is BST", "FA512", time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC), true},
+			{"before opening in BST", "FA512", time.Date(2024, 4, 1, 7, 59, 0, 0, time.UTC), false},
+			{"24 hours", "ZZ999", london(2024, 12, 25, 3, 0), true},
+			{"weekdays only", "FD294", london(2024, 12, 22, 12, 0), false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := repo.IsOpen(ctx, tt.code, tt.at)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("got: %v, but want %v", got, tt.want)
+				}
+				// the repo agrees with the opening hours it returns
+				h, err := repo.OpeningHours(ctx, tt.code)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if h.IsOpen(tt.at) != tt.want {
+					t.Errorf("got: %v from OpeningHours.IsOpen, but want %v", !tt.want, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("find open near", func(t *testing.T) {
+		repo := withHours(t)
+		origin := *Pharmacies()[1].LatLng
+		tests := []struct {
+			name  string
+			at    time.Time
+			limit int
+			want  []string
+		}{
+			// ZZ999 is always open but has no coordinates
+			{"daytime", london(2024, 12, 23, 10, 0), 10, []string{"FC826", "FD294", "FA512"}},
+			{"limit", london(2024, 12, 23, 10, 0), 2, []string{"FC826", "FD294"}},
+			{"late night", london(2024, 12, 23, 23, 0), 10, []string{"FC826"}},
+			{"christmas day", london(2024, 12, 25, 12, 0), 10, []string{"FD294"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				open, err := repo.FindOpenNear(ctx, origin, tt.at, tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make([]string, 0, len(open))
+				for _, n := range open {
+					got = append(got, n.Code)
+					if want := pharmacy.Distance(origin, *n.LatLng); math.Abs(n.Distance-want) > 1 {
+						t.Errorf("got distance %v for %s, but want %v", n.Distance, n.Code, want)
+					}
+				}
+				assertCodes(t, got, tt.want)
+			})
+		}
+
+		if _, err := repo.FindOpenNear(ctx, origin, time.Now(), 0); !errors.Is(err, pharmacy.ErrInvalidInput) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+		}
+	})
+}
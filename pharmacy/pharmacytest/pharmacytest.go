@@ -0,0 +1,674 @@
+// Package pharmacytest is a conformance suite for pharmacy.Repo implementations. Every
+// backend runs it from its own tests to prove they all behave the same way.
+package pharmacytest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// Pharmacies returns the fixtures the suite inserts, taken from sample-pharmacies.csv plus
+// one with every nullable column NULL.
+func Pharmacies() []pharmacy.Pharmacy {
+	return []pharmacy.Pharmacy{
+		{
+			Code:      "FA512",
+			Name:      "Lords Pharmacy",
+			AddrLine1: sql.NullString{String: "", Valid: true},
+			AddrLine2: pharmacy.NullString("Unit 61 The Guineas Shopping Centre"),
+			AddrLine4: pharmacy.NullString("Newmarket"),
+			Postcode:  "CB8 8EQ",
+			Phone:     pharmacy.NullString("01638 428022"),
+			LatLng:    &pharmacy.LatLng{Lat: 52.244797, Lng: 0.405598},
+		},
+		{
+			Code:      "FC826",
+			Name:      "Rutland Late Night Pharmacy",
+			AddrLine2: pharmacy.NullString("45c High Street"),
+			AddrLine4: pharmacy.NullString("Oakham"),
+			Postcode:  "LE15 6AJ",
+			Phone:     pharmacy.NullString("01572 723368"),
+			LatLng:    &pharmacy.LatLng{Lat: 52.670025, Lng: -0.730286},
+		},
+		{
+			Code:      "FD294",
+			Name:      "Cohens Chemist",
+			AddrLine1: pharmacy.NullString("33 Hill Street"),
+			AddrLine4: pharmacy.NullString("Hinckley"),
+			Postcode:  "LE10 1DS",
+			Phone:     pharmacy.NullString("01455613100"),
+			LatLng:    &pharmacy.LatLng{Lat: 52.541035, Lng: -1.367738},
+		},
+		{
+			Code:     "ZZ999",
+			Name:     "Nulls Pharmacy",
+			Postcode: "LE2 1AA",
+		},
+	}
+}
+
+// Run runs the suite. newRepo is called for every test and must return a Repo with no
+// pharmacies, history or opening hours in it. defaultActor is the actor its history records
+// for a write whose context has none, such as the database user.
+func Run(t *testing.T, newRepo func(t *testing.T) pharmacy.Repo, defaultActor string) {
+	ctx := context.Background()
+
+	seeded := func(t *testing.T) pharmacy.Repo {
+		repo := newRepo(t)
+		for _, p := range Pharmacies() {
+			if err := repo.Insert(ctx, p); err != nil {
+				t.Fatalf("could not insert fixture %s: %v", p.Code, err)
+			}
+		}
+		return repo
+	}
+
+	t.Run("find by code", func(t *testing.T) {
+		repo := seeded(t)
+		for _, want := range Pharmacies() {
+			got, err := repo.FindByCode(ctx, want.Code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, *got, want)
+		}
+	})
+
+	t.Run("find by code not found", func(t *testing.T) {
+		repo := seeded(t)
+		_, err := repo.FindByCode(ctx, "XX000")
+		if !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	t.Run("find by codes", func(t *testing.T) {
+		repo := seeded(t)
+		found, missing, err := repo.FindByCodes(ctx, []string{"FD294", "XX000", "FA512", "FD294", "YY000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 2 {
+			t.Errorf("got %d pharmacies, but want 2", len(found))
+		}
+		fixtures := Pharmacies()
+		for _, want := range []pharmacy.Pharmacy{fixtures[0], fixtures[2]} {
+			got, ok := found[want.Code]
+			if !ok {
+				t.Fatalf("got no %s in %v", want.Code, found)
+			}
+			assertEqual(t, *got, want)
+		}
+		assertCodes(t, missing, []string{"XX000", "YY000"})
+
+		found, missing, err = repo.FindByCodes(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 0 || len(missing) != 0 {
+			t.Errorf("got: %v %v, but want nothing for no codes", found, missing)
+		}
+	})
+
+	t.Run("insert duplicate code", func(t *testing.T) {
+		repo := seeded(t)
+		err := repo.Insert(ctx, Pharmacies()[0])
+		if !errors.Is(err, pharmacy.ErrDuplicateCode) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrDuplicateCode)
+		}
+		var dupErr *pharmacy.DuplicateCodeError
+		if !errors.As(err, &dupErr) || dupErr.Code != Pharmacies()[0].Code {
+			t.Errorf("got: %#v, but want a DuplicateCodeError for %s", err, Pharmacies()[0].Code)
+		}
+	})
+
+	t.Run("insert invalid", func(t *testing.T) {
+		repo := newRepo(t)
+		tests := []struct {
+			name   string
+			p      pharmacy.Pharmacy
+			column string
+		}{
+			{"no code", pharmacy.Pharmacy{Name: "No code"}, "code"},
+			{"name too long", pharmacy.Pharmacy{Code: "XX001", Name: strings.Repeat("x", 51)}, "name"},
+			{"phone too long", pharmacy.Pharmacy{Code: "XX002", Phone: pharmacy.NullString(strings.Repeat("1", 21))}, "phone"},
+			{"latitude", pharmacy.Pharmacy{Code: "XX003", LatLng: &pharmacy.LatLng{Lat: 91}}, "lat"},
+		}
+		for _, tt := range tests {
+			err := repo.Insert(ctx, tt.p)
+			if !errors.Is(err, pharmacy.ErrInvalidInput) {
+				t.Errorf("%s got: %v, but want %v", tt.name, err, pharmacy.ErrInvalidInput)
+			}
+			var conErr *pharmacy.ConstraintError
+			if !errors.As(err, &conErr) || conErr.Column != tt.column {
+				t.Errorf("%s got: %#v, but want a ConstraintError for %s", tt.name, err, tt.column)
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := seeded(t)
+		want := Pharmacies()[0]
+		want.Name = "Lords Pharmacy Updated"
+		want.Phone = sql.NullString{}
+		want.LatLng = nil
+		if err := repo.Update(ctx, want); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.FindByCode(ctx, want.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, *got, want)
+	})
+
+	t.Run("update invalid", func(t *testing.T) {
+		repo := seeded(t)
+		p := Pharmacies()[0]
+		p.Postcode = "NOT A POSTCODE"
+		err := repo.Update(ctx, p)
+		if !errors.Is(err, pharmacy.ErrInvalidInput) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+		}
+	})
+
+	t.Run("update not found", func(t *testing.T) {
+		repo := seeded(t)
+		err := repo.Update(ctx, pharmacy.Pharmacy{Code: "XX000", Name: "Missing"})
+		if !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := seeded(t)
+		code := Pharmacies()[0].Code
+		if err := repo.Delete(ctx, code); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := repo.FindByCode(ctx, code)
+		if !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		repo := seeded(t)
+		err := repo.Delete(ctx, "XX000")
+		if !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		repo := newRepo(t)
+		want := Pharmacies()[1]
+		if err := repo.Upsert(ctx, want); err != nil {
+			t.Fatal(err)
+		}
+
+		want.Name = "Rutland Pharmacy"
+		if err := repo.Upsert(ctx, want); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.FindByCode(ctx, want.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, *got, want)
+	})
+
+	t.Run("find by postcode", func(t *testing.T) {
+		repo := seeded(t)
+		pharmacies, err := repo.FindByPostcode(ctx, "LE")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := codes(pharmacies)
+		sort.Strings(got)
+		assertCodes(t, got, []string{"FC826", "FD294", "ZZ999"})
+	})
+
+	t.Run("find by postcode modes", func(t *testing.T) {
+		repo := seeded(t)
+		tests := []struct {
+			partial string
+			want    []string
+		}{
+			{"le", []string{"FD294", "FC826", "ZZ999"}},
+			{"L", []string{}},
+			{"LE1", []string{}},
+			{"le15", []string{"FC826"}},
+			{"LE15 6", []string{"FC826"}},
+			{"LE15 7", []string{}},
+			{"le21aa", []string{"ZZ999"}},
+		}
+		for _, tt := range tests {
+			pharmacies, err := repo.FindByPostcode(ctx, tt.partial)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.partial, err)
+			}
+			assertCodes(t, codes(pharmacies), tt.want)
+		}
+	})
+
+	t.Run("find by postcode wildcards", func(t *testing.T) {
+		repo := seeded(t)
+		for _, partial := range []string{"%", "LE_", "L%"} {
+			_, err := repo.FindByPostcode(ctx, partial)
+			if !errors.Is(err, pharmacy.ErrInvalidInput) {
+				t.Errorf("%s got: %v, but want %v", partial, err, pharmacy.ErrInvalidInput)
+			}
+			_, err = repo.FindByPostcodePage(ctx, partial, "", 10)
+			if !errors.Is(err, pharmacy.ErrInvalidInput) {
+				t.Errorf("%s page got: %v, but want %v", partial, err, pharmacy.ErrInvalidInput)
+			}
+		}
+	})
+
+	t.Run("insert canonical postcode", func(t *testing.T) {
+		repo := newRepo(t)
+		p := pharmacy.Pharmacy{Code: "XX001", Name: "Lower case", Postcode: " le1  5ab"}
+		if err := repo.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.FindByCode(ctx, p.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Postcode != "LE1 5AB" {
+			t.Errorf("got: %q, but want %q", got.Postcode, "LE1 5AB")
+		}
+
+		p.Postcode = "not a postcode"
+		var conErr *pharmacy.ConstraintError
+		if err := repo.Upsert(ctx, p); !errors.As(err, &conErr) || conErr.Column != "postcode" {
+			t.Errorf("got: %v, but want a ConstraintError for postcode", err)
+		}
+	})
+
+	t.Run("short code", func(t *testing.T) {
+		repo := newRepo(t)
+		// postgres pads a code shorter than the char(5) column, so it must trim it when read
+		p := pharmacy.Pharmacy{Code: "NEW1", Name: "Short Code", Postcode: "LE1 5AB"}
+		if err := repo.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.FindByCode(ctx, p.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, *got, p)
+
+		pharmacies, err := repo.FindByPostcode(ctx, "LE1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCodes(t, codes(pharmacies), []string{"NEW1"})
+
+		found, missing, err := repo.FindByCodes(ctx, []string{"NEW1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(missing) != 0 || found["NEW1"] == nil || found["NEW1"].Code != "NEW1" {
+			t.Errorf("got: %v missing %v, but want NEW1 found", found, missing)
+		}
+
+		history, err := repo.History(ctx, p.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Code != "NEW1" || history[0].After == nil || history[0].After.Code != "NEW1" {
+			t.Errorf("got: %+v, but want one insert of NEW1", history)
+		}
+	})
+
+	t.Run("find by postcode page", func(t *testing.T) {
+		repo := seeded(t)
+		var got []string
+		cursor := ""
+		for i := 0; i < 10; i++ {
+			page, err := repo.FindByPostcodePage(ctx, "", cursor, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, codes(page.Pharmacies)...)
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+
+		// ordered by postcode then code
+		assertCodes(t, got, []string{"FA512", "FD294", "FC826", "ZZ999"})
+	})
+
+	t.Run("find by postcode without postcode", func(t *testing.T) {
+		repo := seeded(t)
+		// postgres stores no postcode as NULL
+		if err := repo.Insert(ctx, pharmacy.Pharmacy{Code: "XX002", Name: "No Postcode"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// an empty partial returns every pharmacy, with no postcode first
+		pharmacies, err := repo.FindByPostcode(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCodes(t, codes(pharmacies), []string{"XX002", "FA512", "FD294", "FC826", "ZZ999"})
+
+		page, err := repo.FindByPostcodePage(ctx, "", "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCodes(t, codes(page.Pharmacies), []string{"XX002"})
+		page, err = repo.FindByPostcodePage(ctx, "", page.Next, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCodes(t, codes(page.Pharmacies), []string{"FA512"})
+
+		pharmacies, err = repo.FindByPostcode(ctx, "LE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCodes(t, codes(pharmacies), []string{"FD294", "FC826", "ZZ999"})
+	})
+
+	t.Run("find by postcode page invalid cursor", func(t *testing.T) {
+		repo := seeded(t)
+		_, err := repo.FindByPostcodePage(ctx, "", "not a cursor", 1)
+		if !errors.Is(err, pharmacy.ErrInvalidCursor) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidCursor)
+		}
+	})
+
+	t.Run("find nearest", func(t *testing.T) {
+		repo := seeded(t)
+		origin := *Pharmacies()[1].LatLng
+		nearest, err := repo.FindNearest(ctx, origin, 50000, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, n := range nearest {
+			got = append(got, n.Code)
+			want := pharmacy.Distance(origin, *n.LatLng)
+			if math.Abs(n.Distance-want) > 1 {
+				t.Errorf("got distance %v for %s, but want %v", n.Distance, n.Code, want)
+			}
+		}
+		// Newmarket is about 80km away and the NULL coordinates pharmacy is never near
+		assertCodes(t, got, []string{"FC826", "FD294"})
+
+		nearest, err = repo.FindNearest(ctx, origin, 50000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nearest) != 1 {
+			t.Errorf("got %d pharmacies, but want limit of 1", len(nearest))
+		}
+	})
+
+	t.Run("find nearest invalid", func(t *testing.T) {
+		repo := seeded(t)
+		_, err := repo.FindNearest(ctx, pharmacy.LatLng{}, 0, 10)
+		if !errors.Is(err, pharmacy.ErrInvalidInput) {
+			t.Errorf("got: %v, but want %v", err, pharmacy.ErrInvalidInput)
+		}
+	})
+	t.Run("search by name", func(t *testing.T) {
+		repo := seeded(t)
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"Rutland Oakham", []string{"FC826"}},
+			{"cohens", []string{"FD294"}},
+			// a typo is still found by trigram similarity
+			{"Hinckly", []string{"FD294"}},
+			{"tesco", []string{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				found, err := repo.SearchByName(ctx, tt.query, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertCodes(t, scoredCodes(found), tt.want)
+				for _, f := range found {
+					if f.Score <= 0 {
+						t.Errorf("got score %v for %s, but want > 0", f.Score, f.Code)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("search by name ranking", func(t *testing.T) {
+		repo := seeded(t)
+		found, err := repo.SearchByName(ctx, "pharmacy", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := scoredCodes(found)
+		sort.Strings(got)
+		assertCodes(t, got, []string{"FA512", "FC826", "ZZ999"})
+		for i := 1; i < len(found); i++ {
+			if found[i].Score > found[i-1].Score {
+				t.Errorf("got score %v after %v, but want best first", found[i].Score, found[i-1].Score)
+			}
+		}
+
+		// matching both words beats matching one
+		found, err = repo.SearchByName(ctx, "oakham pharmacy", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) == 0 || found[0].Code != "FC826" {
+			t.Errorf("got: %v, but want FC826 first", scoredCodes(found))
+		}
+
+		found, err = repo.SearchByName(ctx, "pharmacy", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 2 {
+			t.Errorf("got %d pharmacies, but want limit of 2", len(found))
+		}
+	})
+
+	t.Run("search by name invalid", func(t *testing.T) {
+		repo := seeded(t)
+		for _, tt := range []struct {
+			query string
+			limit int
+		}{{"", 10}, {" '! ", 10}, {"pharmacy", 0}} {
+			_, err := repo.SearchByName(ctx, tt.query, tt.limit)
+			if !errors.Is(err, pharmacy.ErrInvalidInput) {
+				t.Errorf("got: %v for %q limit %d, but want %v", err, tt.query, tt.limit, pharmacy.ErrInvalidInput)
+			}
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := pharmacy.WithActor(ctx, "alice")
+		inserted := Pharmacies()[0]
+		if err := repo.Insert(ctx, inserted); err != nil {
+			t.Fatal(err)
+		}
+		updated := inserted
+		updated.Name = "Lords Pharmacy Updated"
+		for i := 0; i < 2; i++ {
+			// the second update changes nothing so is not recorded
+			if err := repo.Update(ctx, updated); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Delete(pharmacy.WithActor(ctx, "bob"), inserted.Code); err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := repo.History(ctx, inserted.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 3 {
+			t.Fatalf("got %d changes, but want 3", len(history))
+		}
+		want := []struct {
+			op, actor     string
+			before, after *pharmacy.Pharmacy
+		}{
+			{pharmacy.OpInsert, "alice", nil, &inserted},
+			{pharmacy.OpUpdate, "alice", &inserted, &updated},
+			{pharmacy.OpDelete, "bob", &updated, nil},
+		}
+		for i, c := range history {
+			if c.Code != inserted.Code || c.Operation != want[i].op || c.Actor != want[i].actor {
+				t.Errorf("got change %d: %s %s by %s, but want %s by %s", i, c.Code, c.Operation, c.Actor, want[i].op, want[i].actor)
+			}
+			assertChanged(t, c.Before, want[i].before)
+			assertChanged(t, c.After, want[i].after)
+			if i > 0 && (c.ID <= history[i-1].ID || c.ChangedAt.Before(history[i-1].ChangedAt)) {
+				t.Errorf("got change %d at %v id %d, but want it after %v id %d", i, c.ChangedAt, c.ID,
+					history[i-1].ChangedAt, history[i-1].ID)
+			}
+		}
+	})
+
+	t.Run("history without actor", func(t *testing.T) {
+		repo := seeded(t)
+		history, err := repo.History(ctx, Pharmacies()[0].Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Actor != defaultActor {
+			t.Errorf("got: %+v, but want one insert by %s", history, defaultActor)
+		}
+
+		history, err = repo.History(ctx, "XX000")
+		if err != nil || history == nil || len(history) != 0 {
+			t.Errorf("got: %v %v, but want an empty history", history, err)
+		}
+	})
+
+	t.Run("find by code as of", func(t *testing.T) {
+		repo := newRepo(t)
+		v1 := Pharmacies()[1]
+		if err := repo.Insert(ctx, v1); err != nil {
+			t.Fatal(err)
+		}
+		v2 := v1
+		v2.Phone = sql.NullString{}
+		v2.LatLng = nil
+		if err := repo.Upsert(ctx, v2); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Delete(ctx, v1.Code); err != nil {
+			t.Fatal(err)
+		}
+		history, err := repo.History(ctx, v1.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 3 {
+			t.Fatalf("got %d changes, but want 3", len(history))
+		}
+
+		for i, want := range []*pharmacy.Pharmacy{&v1, &v2, nil} {
+			got, err := repo.FindByCodeAsOf(ctx, v1.Code, history[i].ChangedAt)
+			if want == nil {
+				if !errors.Is(err, pharmacy.ErrNotFound) {
+					t.Errorf("got: %v after the delete, but want %v", err, pharmacy.ErrNotFound)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, *got, *want)
+		}
+
+		_, err = repo.FindByCodeAsOf(ctx, v1.Code, history[0].ChangedAt.Add(-time.Millisecond))
+		if !errors.Is(err, pharmacy.ErrNotFound) {
+			t.Errorf("got: %v before the insert, but want %v", err, pharmacy.ErrNotFound)
+		}
+	})
+
+	runHours(t, newRepo, seeded)
+}
+
+func codes(pharmacies []*pharmacy.Pharmacy) []string {
+	codes := make([]string, 0, len(pharmacies))
+	for _, p := range pharmacies {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
+func scoredCodes(found []*pharmacy.ScoredPharmacy) []string {
+	codes := make([]string, 0, len(found))
+	for _, f := range found {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+func assertCodes(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got: %v, but want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got: %v, but want %v", got, want)
+		}
+	}
+}
+
+// assertChanged compares the pharmacy before or after a change, either of which may be nil.
+func assertChanged(t *testing.T, got, want *pharmacy.Pharmacy) {
+	t.Helper()
+	if (got == nil) != (want == nil) {
+		t.Errorf("got: %+v, but want %+v", got, want)
+		return
+	}
+	if got != nil {
+		assertEqual(t, *got, *want)
+	}
+}
+
+// assertEqual compares pharmacies allowing for the float rounding of coordinates stored
+// as decimal.
+func assertEqual(t *testing.T, got, want pharmacy.Pharmacy) {
+	t.Helper()
+	gotLatLng, wantLatLng := got.LatLng, want.LatLng
+	got.LatLng, want.LatLng = nil, nil
+	if got != want {
+		t.Errorf("got: %+v, but want %+v", got, want)
+	}
+
+	if (gotLatLng == nil) != (wantLatLng == nil) {
+		t.Fatalf("got LatLng: %v, but want %v", gotLatLng, wantLatLng)
+	}
+	if gotLatLng != nil && (math.Abs(float64(gotLatLng.Lat-wantLatLng.Lat)) > 1e-5 ||
+		math.Abs(float64(gotLatLng.Lng-wantLatLng.Lng)) > 1e-5) {
+		t.Errorf("got LatLng: %v, but want %v", *gotLatLng, *wantLatLng)
+	}
+}
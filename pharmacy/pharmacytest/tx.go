@@ -0,0 +1,199 @@
+package pharmacytest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// WithTx adapts the WithTx method of a backend, which takes a func of its own repo type,
+// to pharmacy.Repo. The repo passed to fn must be usable with WithTx again for nesting.
+type WithTx func(ctx context.Context, repo pharmacy.Repo, opts sql.TxOptions, fn func(tx pharmacy.Repo) error) error
+
+// RunTx runs the transaction tests. newRepo is called for every test and must return a
+// Repo with no pharmacies in it.
+func RunTx(t *testing.T, newRepo func(t *testing.T) pharmacy.Repo, withTx WithTx) {
+	ctx := context.Background()
+	fixtures := Pharmacies()
+	errFailed := errors.New("failed")
+
+	// assertExists checks if code is in repo.
+	assertExists := func(t *testing.T, repo pharmacy.Repo, code string, want bool) {
+		t.Helper()
+		_, err := repo.FindByCode(ctx, code)
+		if got := err == nil; got != want {
+			t.Errorf("got %s exists %v (%v), but want %v", code, got, err, want)
+		}
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			for _, p := range fixtures {
+				if err := tx.Insert(ctx, p); err != nil {
+					return err
+				}
+			}
+			// the transaction sees its own writes
+			_, err := tx.FindByCode(ctx, fixtures[0].Code)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range fixtures {
+			assertExists(t, repo, p.Code, true)
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			if err := tx.Insert(ctx, fixtures[0]); err != nil {
+				return err
+			}
+			return tx.Insert(ctx, fixtures[0])
+		})
+		var dup *pharmacy.DuplicateCodeError
+		if !errors.As(err, &dup) || dup.Code != fixtures[0].Code {
+			t.Errorf("got: %v, but want a duplicate %s", err, fixtures[0].Code)
+		}
+		assertExists(t, repo, fixtures[0].Code, false)
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		repo := newRepo(t)
+		func() {
+			defer func() {
+				if p := recover(); p != "boom" {
+					t.Errorf("got panic: %v, but want boom", p)
+				}
+			}()
+			_ = withTx(ctx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+				if err := tx.Insert(ctx, fixtures[0]); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+		assertExists(t, repo, fixtures[0].Code, false)
+	})
+
+	t.Run("nested savepoint", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			if err := tx.Insert(ctx, fixtures[0]); err != nil {
+				return err
+			}
+			// the failed nested call only rolls back its own insert
+			err := withTx(ctx, tx, sql.TxOptions{}, func(nested pharmacy.Repo) error {
+				if err := nested.Insert(ctx, fixtures[1]); err != nil {
+					return err
+				}
+				return errFailed
+			})
+			if !errors.Is(err, errFailed) {
+				t.Errorf("got: %v, but want %v", err, errFailed)
+			}
+			return withTx(ctx, tx, sql.TxOptions{}, func(nested pharmacy.Repo) error {
+				return nested.Insert(ctx, fixtures[2])
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertExists(t, repo, fixtures[0].Code, true)
+		assertExists(t, repo, fixtures[1].Code, false)
+		assertExists(t, repo, fixtures[2].Code, true)
+	})
+
+	t.Run("nested failure rolls back outer", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			if err := tx.Insert(ctx, fixtures[0]); err != nil {
+				return err
+			}
+			return withTx(ctx, tx, sql.TxOptions{}, func(nested pharmacy.Repo) error {
+				return errFailed
+			})
+		})
+		if !errors.Is(err, errFailed) {
+			t.Errorf("got: %v, but want %v", err, errFailed)
+		}
+		assertExists(t, repo, fixtures[0].Code, false)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{ReadOnly: true}, func(tx pharmacy.Repo) error {
+			return tx.Insert(ctx, fixtures[0])
+		})
+		if err == nil {
+			t.Error("got no error inserting in a read only transaction")
+		}
+		assertExists(t, repo, fixtures[0].Code, false)
+	})
+
+	t.Run("serializable", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx pharmacy.Repo) error {
+			if _, err := tx.FindByPostcode(ctx, "LE"); err != nil {
+				return err
+			}
+			return tx.Upsert(ctx, fixtures[1])
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertExists(t, repo, fixtures[1].Code, true)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		repo := newRepo(t)
+		actorCtx := pharmacy.WithActor(ctx, "carol")
+		err := withTx(actorCtx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			return tx.Insert(actorCtx, fixtures[0])
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = withTx(actorCtx, repo, sql.TxOptions{}, func(tx pharmacy.Repo) error {
+			if err := tx.Insert(actorCtx, fixtures[1]); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Errorf("got: %v, but want %v", err, errFailed)
+		}
+
+		history, err := repo.History(ctx, fixtures[0].Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Actor != "carol" {
+			t.Errorf("got: %+v, but want one change by carol", history)
+		}
+		// a rolled back change is not in the history
+		history, err = repo.History(ctx, fixtures[1].Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 0 {
+			t.Errorf("got: %d changes, but want none", len(history))
+		}
+	})
+
+	t.Run("unsupported isolation", func(t *testing.T) {
+		repo := newRepo(t)
+		err := withTx(ctx, repo, sql.TxOptions{Isolation: sql.LevelLinearizable}, func(tx pharmacy.Repo) error {
+			return nil
+		})
+		if err == nil {
+			t.Error("got no error for linearizable isolation")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package pharmacytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// Watch adapts the Watch method of a backend to pharmacy.Repo.
+type Watch func(ctx context.Context, repo pharmacy.Repo) <-chan pharmacy.ChangeEvent
+
+// RunWatch runs the change feed tests. newRepo is called for every test and must return a
+// Repo with no pharmacies in it, and disconnect must drop the connection of every watch.
+func RunWatch(t *testing.T, newRepo func(t *testing.T) pharmacy.Repo, watch Watch, disconnect func(t *testing.T)) {
+	ctx := context.Background()
+	p := Pharmacies()[0]
+
+	// next returns the next event, failing the test if there is none soon.
+	next := func(t *testing.T, events <-chan pharmacy.ChangeEvent) pharmacy.ChangeEvent {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("got the events closed, but want another")
+			}
+			return e
+		case <-time.After(10 * time.Second):
+			t.Fatal("got no event, but want another")
+		}
+		return pharmacy.ChangeEvent{}
+	}
+	assertEvent := func(t *testing.T, got, want pharmacy.ChangeEvent) {
+		t.Helper()
+		if got != want {
+			t.Errorf("got: %+v, but want %+v", got, want)
+		}
+	}
+	resync := pharmacy.ChangeEvent{Operation: pharmacy.OpResync}
+
+	t.Run("changes", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events := watch(ctx, repo)
+		assertEvent(t, next(t, events), resync)
+
+		if err := repo.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		// an update that changes nothing is not sent
+		if err := repo.Update(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		changed := p
+		changed.Name = "Changed"
+		if err := repo.Update(ctx, changed); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Delete(ctx, p.Code); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, op := range []string{pharmacy.OpInsert, pharmacy.OpUpdate, pharmacy.OpDelete} {
+			assertEvent(t, next(t, events), pharmacy.ChangeEvent{Code: p.Code, Operation: op})
+		}
+	})
+
+	t.Run("short code", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events := watch(ctx, repo)
+		assertEvent(t, next(t, events), resync)
+
+		// a code shorter than the column is sent as it is looked up, not padded
+		short := p
+		short.Code = "NEW1"
+		if err := repo.Insert(ctx, short); err != nil {
+			t.Fatal(err)
+		}
+		assertEvent(t, next(t, events), pharmacy.ChangeEvent{Code: "NEW1", Operation: pharmacy.OpInsert})
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events := watch(ctx, repo)
+		assertEvent(t, next(t, events), resync)
+
+		disconnect(t)
+		assertEvent(t, next(t, events), resync)
+		if err := repo.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		assertEvent(t, next(t, events), pharmacy.ChangeEvent{Code: p.Code, Operation: pharmacy.OpInsert})
+	})
+
+	t.Run("closed when cancelled", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx, cancel := context.WithCancel(ctx)
+		events := watch(ctx, repo)
+		assertEvent(t, next(t, events), resync)
+
+		cancel()
+		select {
+		case e, ok := <-events:
+			if ok {
+				t.Errorf("got: %+v, but want the events closed", e)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("got the events open, but want them closed")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// Package postcode parses and normalises UK postcodes, and the partial postcodes used to
+// search for pharmacies by area, district or sector.
+//
+// A postcode such as LE1 5AB is an outward code LE1, made of the area LE and district
+// number 1, and an inward code 5AB, made of the sector 5 and unit AB. The canonical form
+// is upper case with a single space between the outward and inward codes.
+package postcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned for a value that is not a postcode or partial postcode.
+var ErrInvalid = errors.New("invalid postcode")
+
+var (
+	area     = `[A-Z]{1,2}`
+	outward  = area + `[0-9][A-Z0-9]?`
+	full     = regexp.MustCompile(`^(` + outward + `) ?([0-9][ABD-HJLNP-UW-Z]{2})$`)
+	areaOnly = regexp.MustCompile(`^` + area + `$`)
+	district = regexp.MustCompile(`^` + outward + `$`)
+	sector   = regexp.MustCompile(`^` + outward + ` [0-9]$`)
+)
+
+// Postcode is a full UK postcode.
+type Postcode struct {
+	Outward string
+	Inward  string
+}
+
+// Parse returns the postcode in s, ignoring case and spacing, e.g. "le15ab" is LE1 5AB.
+func Parse(s string) (Postcode, error) {
+	m := full.FindStringSubmatch(clean(s))
+	if m == nil {
+		return Postcode{}, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	return Postcode{Outward: m[1], Inward: m[2]}, nil
+}
+
+// Normalize returns s in canonical form, or an error if it is not a postcode.
+func Normalize(s string) (string, error) {
+	p, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
+// String returns the canonical form of p.
+func (p Postcode) String() string {
+	return p.Outward + " " + p.Inward
+}
+
+// Area returns the letters at the start of the outward code, e.g. LE for LE1 5AB.
+func (p Postcode) Area() string {
+	return p.Outward[:strings.IndexAny(p.Outward, "0123456789")]
+}
+
+// Mode is the part of a postcode a Query matches on.
+type Mode int
+
+const (
+	All Mode = iota
+	Area
+	District
+	Sector
+	Unit
+)
+
+func (m Mode) String() string {
+	switch m {
+	case All:
+		return "all"
+	case Area:
+		return "area"
+	case District:
+		return "district"
+	case Sector:
+		return "sector"
+	case Unit:
+		return "unit"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Query is a search for postcodes in an area (LE), district (LE1), sector (LE1 5) or for
+// a single postcode (LE1 5AB). Prefix is the canonical form of the partial postcode.
+type Query struct {
+	Mode   Mode
+	Prefix string
+}
+
+// ParseQuery returns the Query for a partial postcode, ignoring case and spacing. An
+// empty partial matches every postcode.
+func ParseQuery(partial string) (Query, error) {
+	s := clean(partial)
+	switch {
+	case s == "":
+		return Query{Mode: All}, nil
+	case areaOnly.MatchString(s):
+		return Query{Mode: Area, Prefix: s}, nil
+	case district.MatchString(s):
+		return Query{Mode: District, Prefix: s}, nil
+	case sector.MatchString(s):
+		return Query{Mode: Sector, Prefix: s}, nil
+	}
+	if p, err := Parse(s); err == nil {
+		return Query{Mode: Unit, Prefix: p.String()}, nil
+	}
+	return Query{}, fmt.Errorf("%w search %q, want an area, district, sector or postcode such as LE, LE1, LE1 5 or LE1 5AB", ErrInvalid, partial)
+}
+
+// Like returns the LIKE pattern matching canonical postcodes for q, with any wildcard
+// characters escaped with a backslash. An Area query must also check the character after
+// the prefix is a digit, see Match, so that L does not match LE1 5AB.
+func (q Query) Like() string {
+	p := EscapeLike(q.Prefix)
+	switch q.Mode {
+	case All:
+		return "%"
+	case Area, Sector:
+		return p + "%"
+	case District:
+		// the space stops LE1 matching LE15
+		return p + " %"
+	default:
+		return p
+	}
+}
+
+// Match reports if the canonical postcode pc is matched by q.
+func (q Query) Match(pc string) bool {
+	switch q.Mode {
+	case All:
+		return true
+	case Area:
+		n := len(q.Prefix)
+		return strings.HasPrefix(pc, q.Prefix) && len(pc) > n && pc[n] >= '0' && pc[n] <= '9'
+	case District:
+		return strings.HasPrefix(pc, q.Prefix+" ")
+	case Sector:
+		return strings.HasPrefix(pc, q.Prefix)
+	default:
+		return pc == q.Prefix
+	}
+}
+
+// EscapeLike escapes the LIKE wildcards % and _, and the backslash escape character, so
+// s only matches itself.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// clean upper cases s and collapses runs of white space into one space.
+func clean(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
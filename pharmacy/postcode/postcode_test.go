@@ -0,0 +1,96 @@
+package postcode_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy/postcode"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, want, area string
+	}{
+		{"LE1 5AB", "LE1 5AB", "LE"},
+		{"le15ab", "LE1 5AB", "LE"},
+		{"  le15  6aj ", "LE15 6AJ", "LE"},
+		{"W1A 1AA", "W1A 1AA", "W"},
+		{"EC1A1BB", "EC1A 1BB", "EC"},
+		{"m1 1ae", "M1 1AE", "M"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := postcode.Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.String() != tt.want || p.Area() != tt.area {
+				t.Errorf("got: %s area %s, but want %s area %s", p, p.Area(), tt.want, tt.area)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "LE1", "LE1 5", "LE1 5A", "LE1 5CI", "1LE 5AB", "LE1 5AB%", "LE1_5AB", "ABC1 5AB"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := postcode.Parse(in)
+			if !errors.Is(err, postcode.ErrInvalid) {
+				t.Errorf("got: %v, but want %v", err, postcode.ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		in      string
+		mode    postcode.Mode
+		like    string
+		match   []string
+		nomatch []string
+	}{
+		{"", postcode.All, "%", []string{"LE1 5AB", "CB8 8EQ"}, nil},
+		{"le", postcode.Area, "LE%", []string{"LE1 5AB", "LE15 6AJ"}, []string{"L1 5AB", "CB8 8EQ"}},
+		{"L", postcode.Area, "L%", []string{"L1 5AB"}, []string{"LE1 5AB"}},
+		{"LE1", postcode.District, "LE1 %", []string{"LE1 5AB"}, []string{"LE15 6AJ", "LE10 1DS"}},
+		{"le1 5", postcode.Sector, "LE1 5%", []string{"LE1 5AB"}, []string{"LE1 6AB", "LE15 6AJ"}},
+		{"le15ab", postcode.Unit, "LE1 5AB", []string{"LE1 5AB"}, []string{"LE1 5AD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := postcode.ParseQuery(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Mode != tt.mode || q.Like() != tt.like {
+				t.Errorf("got: %v %q, but want %v %q", q.Mode, q.Like(), tt.mode, tt.like)
+			}
+			for _, pc := range tt.match {
+				if !q.Match(pc) {
+					t.Errorf("got no match for %s, but want one", pc)
+				}
+			}
+			for _, pc := range tt.nomatch {
+				if q.Match(pc) {
+					t.Errorf("got a match for %s, but want none", pc)
+				}
+			}
+		})
+	}
+
+	for _, in := range []string{"%", "LE_", "LE1%", "LE1 5AB 6", "L3 5AB'; drop table pharmacy"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := postcode.ParseQuery(in)
+			if !errors.Is(err, postcode.ErrInvalid) {
+				t.Errorf("got: %v, but want %v", err, postcode.ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	got := postcode.EscapeLike(`50%_off\`)
+	want := `50\%\_off\\`
+	if got != want {
+		t.Errorf("got: %s, but want %s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+)
+
+// Repo retries the idempotent calls of a pharmacy.Repo that fail with an error Retryable
+// reports as transient. Insert is never retried as a lost reply would turn a successful
+// insert into a duplicate code error, use Upsert to write safely with retries.
+//
+// Update, Delete, Upsert and SetOpeningHours are retried as repeating them leaves the
+// same rows, and an update that changes nothing is not recorded in the history of the
+// pharmacy. A Delete whose first attempt succeeded but whose reply was lost returns
+// pharmacy.ErrNotFound.
+type Repo struct {
+	pharmacy.Repo
+	Policy    Policy
+	Retryable func(error) bool
+}
+
+var _ pharmacy.Repo = Repo{}
+
+// NewRepo returns repo retried with policy, where retryable reports the errors of repo that
+// are transient, such as the Transient function of each postgres backend.
+func NewRepo(repo pharmacy.Repo, policy Policy, retryable func(error) bool) Repo {
+	return Repo{Repo: repo, Policy: policy, Retryable: retryable}
+}
+
+func (r Repo) FindByCode(ctx context.Context, code string) (p *pharmacy.Pharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		p, err = r.Repo.FindByCode(ctx, code)
+		return err
+	})
+	return p, err
+}
+
+func (r Repo) FindByCodes(ctx context.Context, codes []string) (found map[string]*pharmacy.Pharmacy, missing []string, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		found, missing, err = r.Repo.FindByCodes(ctx, codes)
+		return err
+	})
+	return found, missing, err
+}
+
+func (r Repo) FindByPostcode(ctx context.Context, partial string) (pharmacies []*pharmacy.Pharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		pharmacies, err = r.Repo.FindByPostcode(ctx, partial)
+		return err
+	})
+	return pharmacies, err
+}
+
+func (r Repo) FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (page *pharmacy.Page, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		page, err = r.Repo.FindByPostcodePage(ctx, partial, cursor, size)
+		return err
+	})
+	return page, err
+}
+
+func (r Repo) FindNearest(ctx context.Context, origin pharmacy.LatLng, radiusMeters float64, limit int) (nearest []*pharmacy.NearbyPharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		nearest, err = r.Repo.FindNearest(ctx, origin, radiusMeters, limit)
+		return err
+	})
+	return nearest, err
+}
+
+func (r Repo) SearchByName(ctx context.Context, query string, limit int) (found []*pharmacy.ScoredPharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		found, err = r.Repo.SearchByName(ctx, query, limit)
+		return err
+	})
+	return found, err
+}
+
+func (r Repo) History(ctx context.Context, code string) (history []*pharmacy.Change, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		history, err = r.Repo.History(ctx, code)
+		return err
+	})
+	return history, err
+}
+
+func (r Repo) FindByCodeAsOf(ctx context.Context, code string, at time.Time) (p *pharmacy.Pharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		p, err = r.Repo.FindByCodeAsOf(ctx, code, at)
+		return err
+	})
+	return p, err
+}
+
+func (r Repo) OpeningHours(ctx context.Context, code string) (h *pharmacy.OpeningHours, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		h, err = r.Repo.OpeningHours(ctx, code)
+		return err
+	})
+	return h, err
+}
+
+func (r Repo) IsOpen(ctx context.Context, code string, t time.Time) (open bool, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		open, err = r.Repo.IsOpen(ctx, code, t)
+		return err
+	})
+	return open, err
+}
+
+func (r Repo) FindOpenNear(ctx context.Context, origin pharmacy.LatLng, t time.Time, limit int) (open []*pharmacy.NearbyPharmacy, err error) {
+	err = r.Policy.Do(ctx, r.Retryable, func() error {
+		open, err = r.Repo.FindOpenNear(ctx, origin, t, limit)
+		return err
+	})
+	return open, err
+}
+
+func (r Repo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	return r.Policy.Do(ctx, r.Retryable, func() error {
+		return r.Repo.Update(ctx, p)
+	})
+}
+
+func (r Repo) Delete(ctx context.Context, code string) error {
+	return r.Policy.Do(ctx, r.Retryable, func() error {
+		return r.Repo.Delete(ctx, code)
+	})
+}
+
+func (r Repo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	return r.Policy.Do(ctx, r.Retryable, func() error {
+		return r.Repo.Upsert(ctx, p)
+	})
+}
+
+func (r Repo) SetOpeningHours(ctx context.Context, code string, h pharmacy.OpeningHours) error {
+	return r.Policy.Do(ctx, r.Retryable, func() error {
+		return r.Repo.SetOpeningHours(ctx, code, h)
+	})
+}
@@ -0,0 +1,78 @@
+// Package retry retries calls that fail with a transient error, such as a dropped database
+// connection, with exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy is an exponential backoff. The wait before each retry starts at InitialInterval
+// and is multiplied by Multiplier up to MaxInterval, then randomised by ±50% so clients
+// that failed together do not retry together.
+type Policy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// MaxElapsedTime stops retrying once the next attempt would start this long after
+	// the first. Zero retries until the context is done.
+	MaxElapsedTime time.Duration
+	// OnRetry, if set, is called with the error of each failed attempt that will be
+	// retried, and the wait before the next one.
+	OnRetry func(err error, wait time.Duration)
+}
+
+// Default waits 100ms, doubling up to 5s, for up to 30s.
+var Default = Policy{
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	Multiplier:      2,
+	MaxElapsedTime:  30 * time.Second,
+}
+
+// Do calls fn until it succeeds, it returns an error that is not retryable, ctx is done or
+// MaxElapsedTime has passed. It returns the error of the last attempt, or the context
+// error if ctx was done before the first.
+func (p Policy) Do(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	start := time.Now()
+	interval := p.InitialInterval
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := jitter(interval)
+		if p.MaxElapsedTime > 0 && time.Since(start)+wait > p.MaxElapsedTime {
+			return err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * p.Multiplier)
+		if interval > p.MaxInterval {
+			interval = p.MaxInterval
+		}
+	}
+}
+
+// jitter returns d randomised to between half and one and a half times d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d)
+}
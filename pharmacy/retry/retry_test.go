@@ -0,0 +1,191 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/memory"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pharmacytest"
+	"github.com/ayubmalik/go-cookbook/pharmacy/retry"
+)
+
+var errTransient = errors.New("transient")
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+var fast = retry.Policy{InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, Multiplier: 2}
+
+// failing returns a func that fails with err the first n times it is called, and counts
+// the calls.
+func failing(n int, err error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		var waits []time.Duration
+		p := fast
+		p.OnRetry = func(_ error, wait time.Duration) { waits = append(waits, wait) }
+		fn, calls := failing(3, errTransient)
+		if err := p.Do(ctx, isTransient, fn); err != nil {
+			t.Fatal(err)
+		}
+		if *calls != 4 {
+			t.Errorf("got %d calls, but want 4", *calls)
+		}
+		// each wait is the interval ±50%, the interval doubling up to MaxInterval
+		for i, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond} {
+			if waits[i] < want/2 || waits[i] >= want*3/2 {
+				t.Errorf("got wait %d: %v, but want %v ±50%%", i, waits[i], want)
+			}
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		other := errors.New("other")
+		fn, calls := failing(3, other)
+		if err := fast.Do(ctx, isTransient, fn); !errors.Is(err, other) {
+			t.Errorf("got: %v, but want %v", err, other)
+		}
+		if *calls != 1 {
+			t.Errorf("got %d calls, but want 1", *calls)
+		}
+	})
+
+	t.Run("max elapsed time", func(t *testing.T) {
+		p := fast
+		p.MaxElapsedTime = 20 * time.Millisecond
+		fn, _ := failing(1000, errTransient)
+		start := time.Now()
+		if err := p.Do(ctx, isTransient, fn); !errors.Is(err, errTransient) {
+			t.Errorf("got: %v, but want %v", err, errTransient)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("got %v, but want to give up after about %v", elapsed, p.MaxElapsedTime)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := fast
+		p.InitialInterval, p.MaxInterval = time.Hour, time.Hour
+		p.OnRetry = func(error, time.Duration) { cancel() }
+		fn, calls := failing(1000, errTransient)
+		if err := p.Do(ctx, isTransient, fn); !errors.Is(err, errTransient) {
+			t.Errorf("got: %v, but want the last error %v", err, errTransient)
+		}
+		if *calls != 1 {
+			t.Errorf("got %d calls, but want 1", *calls)
+		}
+
+		fn, calls = failing(0, nil)
+		if err := p.Do(ctx, isTransient, fn); !errors.Is(err, context.Canceled) {
+			t.Errorf("got: %v, but want %v", err, context.Canceled)
+		}
+		if *calls != 0 {
+			t.Errorf("got %d calls, but want none once cancelled", *calls)
+		}
+	})
+}
+
+// flakyRepo fails every call with errTransient until it has failed fails times.
+type flakyRepo struct {
+	pharmacy.Repo
+	fails int
+	calls int
+}
+
+func (r *flakyRepo) flaky() error {
+	r.calls++
+	if r.calls <= r.fails {
+		return errTransient
+	}
+	return nil
+}
+
+func (r *flakyRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	if err := r.flaky(); err != nil {
+		return nil, err
+	}
+	return r.Repo.FindByCode(ctx, code)
+}
+
+func (r *flakyRepo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	if err := r.flaky(); err != nil {
+		return err
+	}
+	return r.Repo.Insert(ctx, p)
+}
+
+func (r *flakyRepo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	if err := r.flaky(); err != nil {
+		return err
+	}
+	return r.Repo.Upsert(ctx, p)
+}
+
+func TestRepo(t *testing.T) {
+	ctx := context.Background()
+	newRepo := func(t *testing.T) (retry.Repo, *flakyRepo) {
+		mem, err := memory.New(pharmacytest.Pharmacies()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flaky := &flakyRepo{Repo: mem, fails: 2}
+		return retry.NewRepo(flaky, fast, isTransient), flaky
+	}
+
+	t.Run("conformance", func(t *testing.T) {
+		pharmacytest.Run(t, func(t *testing.T) pharmacy.Repo {
+			mem, err := memory.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return retry.NewRepo(mem, fast, isTransient)
+		}, memory.UnknownActor)
+	})
+
+	t.Run("find retried", func(t *testing.T) {
+		repo, flaky := newRepo(t)
+		if _, err := repo.FindByCode(ctx, "FA512"); err != nil {
+			t.Fatal(err)
+		}
+		if flaky.calls != 3 {
+			t.Errorf("got %d calls, but want 3", flaky.calls)
+		}
+	})
+
+	t.Run("upsert retried", func(t *testing.T) {
+		repo, flaky := newRepo(t)
+		if err := repo.Upsert(ctx, pharmacy.Pharmacy{Code: "NEW1", Name: "New"}); err != nil {
+			t.Fatal(err)
+		}
+		if flaky.calls != 3 {
+			t.Errorf("got %d calls, but want 3", flaky.calls)
+		}
+	})
+
+	t.Run("insert not retried", func(t *testing.T) {
+		repo, flaky := newRepo(t)
+		if err := repo.Insert(ctx, pharmacy.Pharmacy{Code: "NEW1", Name: "New"}); !errors.Is(err, errTransient) {
+			t.Errorf("got: %v, but want %v", err, errTransient)
+		}
+		if flaky.calls != 1 {
+			t.Errorf("got %d calls, but want 1", flaky.calls)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package pharmacy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// WordSimilarityThreshold is the trigram word similarity a name search needs to match
+// without a whole word in common, the same as the pg_trgm default.
+const WordSimilarityThreshold = 0.6
+
+// ScoredPharmacy is a Pharmacy found by SearchByName along with how well it matched, a
+// higher Score being a better match. Scores only compare results from the same backend.
+type ScoredPharmacy struct {
+	Pharmacy
+	Score float64
+}
+
+// CheckSearch validates the SearchByName arguments, returning an error matching
+// ErrInvalidInput if the query has no words or the limit is not positive.
+func CheckSearch(query string, limit int) error {
+	if len(SearchTerms(query)) == 0 || limit <= 0 {
+		return fmt.Errorf("%w: search %q needs a word and limit %d must be positive", ErrInvalidInput, query, limit)
+	}
+	return nil
+}
+
+// SearchText returns the name and address lines of p that a name search looks in.
+func SearchText(p Pharmacy) string {
+	return strings.Join([]string{p.Name, p.AddrLine1.String, p.AddrLine2.String, p.AddrLine3.String,
+		p.AddrLine4.String}, " ")
+}
+
+// NameScore is the pure Go ranking used by backends without pg_trgm. Like the postgres
+// query it adds the trigram word similarity of query to the text of p, the share of the
+// query trigrams found there, to the share of query words found whole. ok is false if p
+// does not match at all.
+func NameScore(query string, p Pharmacy) (score float64, ok bool) {
+	text := SearchText(p)
+	similarity := WordSimilarity(query, text)
+
+	textWords := make(map[string]bool)
+	for _, w := range SearchTerms(text) {
+		textWords[w] = true
+	}
+	queryWords := SearchTerms(query)
+	var found int
+	for _, w := range queryWords {
+		if textWords[w] {
+			found++
+		}
+	}
+
+	if similarity < WordSimilarityThreshold && found == 0 {
+		return 0, false
+	}
+	return similarity + float64(found)/float64(len(queryWords)), true
+}
+
+// WordSimilarity returns the share of the trigrams of query that are also trigrams of
+// text, from 0 to 1. It approximates pg_trgm word_similarity, which looks for the best
+// matching run of words in text rather than all of it.
+func WordSimilarity(query, text string) float64 {
+	q := trigrams(query)
+	if len(q) == 0 {
+		return 0
+	}
+	t := trigrams(text)
+	var shared int
+	for tri := range q {
+		if t[tri] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(q))
+}
+
+// trigrams returns the set of trigrams of the words in s, each word lower cased and padded
+// with two spaces before and one after as pg_trgm does.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range SearchTerms(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// SearchTerms splits s into the lower case words of letters and digits a name search
+// looks for, so "Sainsbury's" is the terms sainsbury and s.
+func SearchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,14 @@
+package pharmacy
+
+// OpResync is the Operation of a ChangeEvent sent each time a watch starts listening,
+// including the first. It has no Code as any pharmacy may have changed before then, so a
+// consumer should reload what it keeps, such as clearing a cache.
+const OpResync = "RESYNC"
+
+// ChangeEvent is sent by a watch when a pharmacy is written, by a repo or any other SQL,
+// once the write commits.
+type ChangeEvent struct {
+	Code string
+	// Operation is OpInsert, OpUpdate, OpDelete or OpResync.
+	Operation string
+}
@@ -1,8 +1,30 @@
-module github.com/ayubmalik/go-cookbook/sql-pgx
+module github.com/ayubmalik/go-cookbook/sql-pgx-pool
 
-go 1.16
+go 1.22
 
 require (
+	github.com/ayubmalik/go-cookbook/config v0.0.0-00010101000000-000000000000
+	github.com/ayubmalik/go-cookbook/pharmacy v0.0.0-00010101000000-000000000000
+	github.com/jackc/pgconn v1.10.1
 	github.com/jackc/pgx/v4 v4.14.1
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.3 // indirect
+	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgproto3/v2 v2.2.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
+	github.com/jackc/pgtype v1.9.1 // indirect
+	github.com/jackc/puddle v1.2.0 // indirect
 	github.com/lib/pq v1.10.4 // indirect
+	golang.org/x/crypto v0.0.0-20210711020723-a769d52b0f97 // indirect
+	golang.org/x/text v0.3.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/ayubmalik/go-cookbook/pharmacy => ../pharmacy
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Import streams the valid rows of a pharmacy CSV into the pharmacy table with
+// the postgres COPY protocol. COPY is all or nothing, so an error such as a duplicate code
+// leaves the table unchanged.
+func Import(ctx context.Context, pool *pgxpool.Pool, r io.Reader) (*pharmacy.ImportStats, error) {
+	start := time.Now()
+	src := &copySource{pharmacies: pharmacy.NewCSVReader(r)}
+	n, err := pool.CopyFrom(ctx, pgx.Identifier{"pharmacy"}, pharmacy.CSVColumns, src)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &pharmacy.ImportStats{
+		Imported: n,
+		Rejected: src.pharmacies.Rejected,
+		Elapsed:  time.Since(start),
+	}, nil
+}
+
+// copySource adapts a pharmacy.CSVReader to pgx.CopyFromSource so rows are read from the CSV
+// as COPY consumes them rather than all loaded up front.
+type copySource struct {
+	pharmacies *pharmacy.CSVReader
+	values     []interface{}
+	err        error
+}
+
+func (s *copySource) Next() bool {
+	p, err := s.pharmacies.Next()
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+
+	s.values = pgsql.Args(*p)
+	return true
+}
+
+func (s *copySource) Values() ([]interface{}, error) {
+	return s.values, nil
+}
+
+func (s *copySource) Err() error {
+	return s.err
+}
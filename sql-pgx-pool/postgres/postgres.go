@@ -0,0 +1,411 @@
+// Package postgres implements pharmacy.Repo with a pgxpool connection pool.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/metrics"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type PSQLPharmacyRepo struct {
+	// Conn is the primary, which every write goes to.
+	Conn *pgxpool.Pool
+	// Replicas, if set, serve the Find methods instead of Conn.
+	Replicas *Replicas
+	// Timeout, if set, bounds every call unless the caller's context has an earlier deadline.
+	Timeout time.Duration
+
+	// tx is set in the repo passed to a WithTx func.
+	tx pgx.Tx
+}
+
+var _ pharmacy.Repo = PSQLPharmacyRepo{}
+
+// Open connects a pool to postgres, tuned by settings. Settings left zero keep the values
+// from the url, such as pool_max_conns, or the pgxpool defaults. A positive
+// statementTimeout is also set as the session statement_timeout, so the server aborts a
+// slow query even if the client goes away.
+func Open(ctx context.Context, url string, statementTimeout time.Duration, settings pgsql.PoolSettings) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	if settings.MaxConns > 0 {
+		config.MaxConns = int32(settings.MaxConns)
+	}
+	if settings.MinConns > 0 {
+		config.MinConns = int32(settings.MinConns)
+	}
+	if settings.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = settings.MaxConnLifetime
+	}
+	if settings.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = settings.MaxConnIdleTime
+	}
+	if settings.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = settings.HealthCheckPeriod
+	}
+	if statementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Metrics returns the statistics of pool, for metrics.Handler.
+func Metrics(pool *pgxpool.Pool) []metrics.Metric {
+	s := pool.Stat()
+	return []metrics.Metric{
+		{Name: "pgxpool_acquires_total", Help: "The total number of successful connection acquires.", Type: metrics.Counter, Value: float64(s.AcquireCount())},
+		{Name: "pgxpool_acquire_duration_seconds_total", Help: "The total time spent in successful acquires, including waiting for a connection.", Type: metrics.Counter, Value: s.AcquireDuration().Seconds()},
+		{Name: "pgxpool_empty_acquires_total", Help: "The total number of successful acquires that waited for a connection because the pool was empty.", Type: metrics.Counter, Value: float64(s.EmptyAcquireCount())},
+		{Name: "pgxpool_canceled_acquires_total", Help: "The total number of acquires canceled by their context.", Type: metrics.Counter, Value: float64(s.CanceledAcquireCount())},
+		{Name: "pgxpool_acquired_conns", Help: "The number of connections currently in use.", Type: metrics.Gauge, Value: float64(s.AcquiredConns())},
+		{Name: "pgxpool_idle_conns", Help: "The number of idle connections.", Type: metrics.Gauge, Value: float64(s.IdleConns())},
+		{Name: "pgxpool_constructing_conns", Help: "The number of connections being opened.", Type: metrics.Gauge, Value: float64(s.ConstructingConns())},
+		{Name: "pgxpool_total_conns", Help: "The number of connections acquired, idle or being opened.", Type: metrics.Gauge, Value: float64(s.TotalConns())},
+		{Name: "pgxpool_max_conns", Help: "The maximum size of the pool.", Type: metrics.Gauge, Value: float64(s.MaxConns())},
+	}
+}
+
+// reader returns where to read from: the transaction of a repo passed to a WithTx func,
+// otherwise a replica unless there are none or the read must see a recent write.
+func (r PSQLPharmacyRepo) reader() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.Replicas.reader(r.Conn)
+}
+
+// primary returns where to read from when the read must see every committed write: the
+// transaction of a repo passed to a WithTx func, otherwise the primary.
+func (r PSQLPharmacyRepo) primary() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.Conn
+}
+
+func (r PSQLPharmacyRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
+
+func (r PSQLPharmacyRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	p, err := pgsql.ScanPharmacy(r.reader().QueryRow(ctx, pgsql.FindByCode, code))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return p, nil
+}
+
+// FindByCodes queues a FindByCode for every code in a pgx.Batch, so they are sent in one
+// round trip. A code that is not found does not fail the others.
+func (r PSQLPharmacyRepo) FindByCodes(ctx context.Context, codes []string) (map[string]*pharmacy.Pharmacy, []string, error) {
+	codes = pharmacy.UniqueCodes(codes)
+	if len(codes) == 0 {
+		return map[string]*pharmacy.Pharmacy{}, []string{}, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, code := range codes {
+		batch.Queue(pgsql.FindByCode, code)
+	}
+	results := r.reader().SendBatch(ctx, batch)
+	defer results.Close()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0, len(codes))
+	for range codes {
+		p, err := pgsql.ScanPharmacy(results.QueryRow())
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, translateError(err)
+		}
+		pharmacies = append(pharmacies, p)
+	}
+	if err := results.Close(); err != nil {
+		return nil, nil, translateError(err)
+	}
+
+	found, missing := pharmacy.MatchCodes(codes, pharmacies)
+	return found, missing, nil
+}
+
+func (r PSQLPharmacyRepo) FindByPostcode(ctx context.Context, partial string) ([]*pharmacy.Pharmacy, error) {
+	query, args, err := pgsql.FindByPostcode(partial)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.query(ctx, query, args...)
+}
+
+// FindByPostcodePage is a paged FindByPostcode. Pass an empty cursor for the first page and
+// Page.Next for the following ones; an empty partial pages through every pharmacy.
+// Pages are read with a keyset on (postcode, code) so deep pages cost the same as the first.
+func (r PSQLPharmacyRepo) FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (*pharmacy.Page, error) {
+	size = pharmacy.PageSize(size)
+	query, args, err := pgsql.FindByPostcodePage(partial, cursor, size)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pharmacies, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgsql.NewPage(pharmacies, size), nil
+}
+
+// FindNearest returns at most limit pharmacies within radiusMeters of origin, nearest first.
+func (r PSQLPharmacyRepo) FindNearest(ctx context.Context, origin pharmacy.LatLng, radiusMeters float64, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	args, err := pgsql.FindNearestArgs(origin, radiusMeters, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.reader().Query(ctx, pgsql.FindNearest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.NearbyPharmacy, 0)
+	for rows.Next() {
+		var distance float64
+		p, err := pgsql.ScanPharmacy(rows, &distance)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.NearbyPharmacy{Pharmacy: *p, Distance: distance})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// SearchByName returns at most limit pharmacies whose name or address matches query,
+// best match first, see pgsql.SearchByName.
+func (r PSQLPharmacyRepo) SearchByName(ctx context.Context, query string, limit int) ([]*pharmacy.ScoredPharmacy, error) {
+	args, err := pgsql.SearchByNameArgs(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.reader().Query(ctx, pgsql.SearchByName, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.ScoredPharmacy, 0)
+	for rows.Next() {
+		var score float64
+		p, err := pgsql.ScanPharmacy(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.ScoredPharmacy{Pharmacy: *p, Score: score})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+func (r PSQLPharmacyRepo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.exec(ctx, pgsql.Insert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+func (r PSQLPharmacyRepo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		tag, err := r.exec(ctx, pgsql.Update, pgsql.Args(p)...)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(tag)
+	})
+}
+
+func (r PSQLPharmacyRepo) Delete(ctx context.Context, code string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		tag, err := r.exec(ctx, pgsql.Delete, code)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(tag)
+	})
+}
+
+// Upsert inserts p or, if a pharmacy with the same code already exists, overwrites it.
+// Unlike Insert it is safe to call repeatedly with the same data.
+func (r PSQLPharmacyRepo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.exec(ctx, pgsql.Upsert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+// History returns the changes recorded by the pharmacy_history trigger, oldest first. It
+// reads the primary, not a replica, so an audit never misses a write already committed.
+func (r PSQLPharmacyRepo) History(ctx context.Context, code string) ([]*pharmacy.Change, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.primary().Query(ctx, pgsql.History, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*pharmacy.Change, 0)
+	for rows.Next() {
+		c, err := pgsql.ScanChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+// FindByCodeAsOf returns the pharmacy as it was at a time, reading the primary like History.
+func (r PSQLPharmacyRepo) FindByCodeAsOf(ctx context.Context, code string, at time.Time) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var after []byte
+	err := r.primary().QueryRow(ctx, pgsql.FindByCodeAsOf, code, pgsql.AsOfArg(at)).Scan(&after)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return pgsql.AsOf(after)
+}
+
+// exec runs a write on the primary, pinning reads to it if Replicas read your writes.
+func (r PSQLPharmacyRepo) exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	defer r.Replicas.wrote()
+	if r.tx != nil {
+		return r.tx.Exec(ctx, sql, args...)
+	}
+	return r.Conn.Exec(ctx, sql, args...)
+}
+
+func (r PSQLPharmacyRepo) query(ctx context.Context, query string, args ...interface{}) ([]*pharmacy.Pharmacy, error) {
+	rows, err := r.reader().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0)
+	for rows.Next() {
+		p, err := pgsql.ScanPharmacy(rows)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, p)
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// checkAffected returns pharmacy.ErrNotFound if an update or delete did not match any row.
+func checkAffected(tag pgconn.CommandTag) error {
+	if tag.RowsAffected() == 0 {
+		return pharmacy.ErrNotFound
+	}
+	return nil
+}
+
+// translateError maps driver errors onto the pharmacy errors every backend returns.
+func translateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pharmacy.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgsql.TranslateError(err, pgErr.Code, pgErr.ColumnName, pgErr.Message, pgErr.Detail)
+	}
+	return err
+}
+
+// Transient reports if err, returned by the repo or Open, is likely to succeed if retried,
+// for retry.Policy.Do and retry.NewRepo.
+func Transient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgsql.Transient(err, pgErr.Code)
+	}
+	return pgsql.Transient(err, "")
+}
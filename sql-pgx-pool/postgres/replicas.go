@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Replicas are read only pools that PSQLPharmacyRepo sends its Find methods to, in turn,
+// skipping any that failed their last health check. Reads fall back to the primary when
+// no replica is healthy. A nil *Replicas sends every read to the primary.
+type Replicas struct {
+	pools []*pgxpool.Pool
+	// readYourWrites is how long reads stay on the primary after a write.
+	readYourWrites time.Duration
+
+	next      atomic.Uint64
+	lastWrite atomic.Int64
+
+	mu      sync.RWMutex
+	healthy []bool
+}
+
+// NewReplicas returns Replicas for pools, which start out healthy. A positive
+// readYourWrites pins reads to the primary for that long after any write through the repo,
+// so callers see their own changes despite replication lag.
+func NewReplicas(pools []*pgxpool.Pool, readYourWrites time.Duration) *Replicas {
+	healthy := make([]bool, len(pools))
+	for i := range healthy {
+		healthy[i] = true
+	}
+	return &Replicas{pools: pools, readYourWrites: readYourWrites, healthy: healthy}
+}
+
+// Check pings every replica, marking those that fail unhealthy until a later Check
+// succeeds.
+func (rs *Replicas) Check(ctx context.Context) {
+	healthy := make([]bool, len(rs.pools))
+	var wg sync.WaitGroup
+	for i, pool := range rs.pools {
+		wg.Add(1)
+		go func(i int, pool *pgxpool.Pool) {
+			defer wg.Done()
+			healthy[i] = pool.Ping(ctx) == nil
+		}(i, pool)
+	}
+	wg.Wait()
+
+	rs.mu.Lock()
+	rs.healthy = healthy
+	rs.mu.Unlock()
+}
+
+// Run calls Check every period until ctx is cancelled.
+func (rs *Replicas) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, period)
+			rs.Check(checkCtx)
+			cancel()
+		}
+	}
+}
+
+// Standalone returns the replicas that are not hot standbys, as reported by
+// pg_is_in_recovery, such as the stand in of docker compose. A streaming replica gets its
+// schema from the primary, so only these need migrating.
+func (rs *Replicas) Standalone(ctx context.Context) ([]*pgxpool.Pool, error) {
+	if rs == nil {
+		return nil, nil
+	}
+	var standalone []*pgxpool.Pool
+	for _, pool := range rs.pools {
+		var recovering bool
+		if err := pool.QueryRow(ctx, `select pg_is_in_recovery()`).Scan(&recovering); err != nil {
+			return nil, err
+		}
+		if !recovering {
+			standalone = append(standalone, pool)
+		}
+	}
+	return standalone, nil
+}
+
+// Close closes every replica pool.
+func (rs *Replicas) Close() {
+	for _, pool := range rs.pools {
+		pool.Close()
+	}
+}
+
+// reader returns the pool for the next read.
+func (rs *Replicas) reader(primary *pgxpool.Pool) *pgxpool.Pool {
+	if rs == nil || len(rs.pools) == 0 {
+		return primary
+	}
+	if rs.readYourWrites > 0 && time.Since(time.Unix(0, rs.lastWrite.Load())) < rs.readYourWrites {
+		return primary
+	}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	n := uint64(len(rs.pools))
+	start := rs.next.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		if j := (start + i) % n; rs.healthy[j] {
+			return rs.pools[j]
+		}
+	}
+	return primary
+}
+
+// wrote records a write to the primary for read your writes.
+func (rs *Replicas) wrote() {
+	if rs != nil && rs.readYourWrites > 0 {
+		rs.lastWrite.Store(time.Now().UnixNano())
+	}
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// querier is implemented by *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// WithTx calls fn with a repo whose calls all run in one transaction with opts on the
+// primary, so several inserts are atomic or a read sees the same data as a following
+// write. The transaction is committed if fn returns nil and rolled back if it returns an
+// error or panics.
+//
+// Calling WithTx on the repo passed to fn runs the nested fn in a savepoint, using pgx
+// pseudo nested transactions, so only its changes are rolled back if it fails, and the
+// outer fn can carry on. opts only apply to the outermost call. The repo passed to fn
+// must not be used concurrently or after fn returns.
+func (r PSQLPharmacyRepo) WithTx(ctx context.Context, opts sql.TxOptions, fn func(tx PSQLPharmacyRepo) error) error {
+	// errors from fn are returned as they are, only those of pgx are translated
+	var fnFailed bool
+	call := func(tx pgx.Tx) error {
+		inTx := r
+		inTx.tx = tx
+		err := fn(inTx)
+		fnFailed = err != nil
+		return err
+	}
+
+	var err error
+	if r.tx != nil {
+		err = r.tx.BeginFunc(ctx, call)
+	} else {
+		var txOpts pgx.TxOptions
+		if txOpts, err = txOptions(opts); err != nil {
+			return err
+		}
+		err = r.Conn.BeginTxFunc(ctx, txOpts, call)
+		if !opts.ReadOnly {
+			// reads of the committed writes should not go to a lagging replica either
+			r.Replicas.wrote()
+		}
+	}
+	if fnFailed {
+		return err
+	}
+	return translateError(err)
+}
+
+// txOptions maps database/sql transaction options onto pgx ones.
+func txOptions(opts sql.TxOptions) (pgx.TxOptions, error) {
+	var txOpts pgx.TxOptions
+	switch opts.Isolation {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		txOpts.IsoLevel = pgx.ReadUncommitted
+	case sql.LevelReadCommitted:
+		txOpts.IsoLevel = pgx.ReadCommitted
+	case sql.LevelRepeatableRead:
+		txOpts.IsoLevel = pgx.RepeatableRead
+	case sql.LevelSerializable:
+		txOpts.IsoLevel = pgx.Serializable
+	default:
+		return txOpts, fmt.Errorf("isolation level %s is not supported by postgres", opts.Isolation)
+	}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+	return txOpts, nil
+}
+
+// audited calls write in a transaction that first sets the actor in ctx, see
+// pharmacy.WithActor, for the pharmacy_history trigger. Without an actor write is called
+// on r as it is, and the trigger records the database user.
+func (r PSQLPharmacyRepo) audited(ctx context.Context, write func(r PSQLPharmacyRepo) error) error {
+	actor, ok := pharmacy.ActorFrom(ctx)
+	if !ok {
+		return write(r)
+	}
+	if r.tx == nil {
+		return r.WithTx(ctx, sql.TxOptions{}, func(tx PSQLPharmacyRepo) error {
+			return tx.audited(ctx, write)
+		})
+	}
+	if _, err := r.tx.Exec(ctx, pgsql.SetActor, actor); err != nil {
+		return translateError(err)
+	}
+	return write(r)
+}
@@ -1,12 +1,28 @@
 module github.com/ayubmalik/go-cookbook/sql-pgx
 
-go 1.21
+go 1.22
 
-require github.com/jackc/pgx/v5 v5.7.2
+require (
+	github.com/ayubmalik/go-cookbook/config v0.0.0-00010101000000-000000000000
+	github.com/ayubmalik/go-cookbook/pharmacy v0.0.0-00010101000000-000000000000
+	github.com/jackc/pgx/v5 v5.7.2
+)
 
 require (
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.3 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	golang.org/x/crypto v0.31.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/ayubmalik/go-cookbook/pharmacy => ../pharmacy
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
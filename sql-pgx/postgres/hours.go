@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+// OpeningHours reads the sessions of code from the pharmacy_opening_hours table of the
+// 0004_opening_hours migration.
+func (r PSQLPharmacyRepo) OpeningHours(ctx context.Context, code string) (*pharmacy.OpeningHours, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().Query(ctx, pgsql.OpeningHours, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours pgsql.HoursScanner
+	for rows.Next() {
+		if err := hours.Scan(rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return hours.OpeningHours()
+}
+
+// SetOpeningHours deletes the sessions of code and inserts those of h in one transaction.
+func (r PSQLPharmacyRepo) SetOpeningHours(ctx context.Context, code string, h pharmacy.OpeningHours) error {
+	args, err := pgsql.SessionArgs(code, h)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.WithTx(ctx, sql.TxOptions{}, func(tx PSQLPharmacyRepo) error {
+		var locked string
+		if err := tx.conn().QueryRow(ctx, pgsql.LockPharmacy, code).Scan(&locked); err != nil {
+			return translateError(err)
+		}
+		if _, err := tx.conn().Exec(ctx, pgsql.DeleteOpeningHours, code); err != nil {
+			return translateError(err)
+		}
+		for _, a := range args {
+			if _, err := tx.conn().Exec(ctx, pgsql.InsertSession, a...); err != nil {
+				return translateError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// IsOpen asks postgres with the pharmacy_is_open function of the 0004_opening_hours
+// migration.
+func (r PSQLPharmacyRepo) IsOpen(ctx context.Context, code string, t time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var open bool
+	if err := r.conn().QueryRow(ctx, pgsql.IsOpen, code, t).Scan(&open); err != nil {
+		return false, translateError(err)
+	}
+	return open, nil
+}
+
+// FindOpenNear returns at most limit pharmacies open at t, nearest to origin first.
+func (r PSQLPharmacyRepo) FindOpenNear(ctx context.Context, origin pharmacy.LatLng, t time.Time, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	args, err := pgsql.FindOpenNearArgs(origin, t, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().Query(ctx, pgsql.FindOpenNear, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.NearbyPharmacy, 0)
+	for rows.Next() {
+		var distance float64
+		p, err := pgsql.ScanPharmacy(rows, &distance)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.NearbyPharmacy{Pharmacy: *p, Distance: distance})
+	}
+
+	return pharmacies, rows.Err()
+}
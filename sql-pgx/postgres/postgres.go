@@ -0,0 +1,352 @@
+// Package postgres implements pharmacy.Repo with a single pgx connection.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PSQLPharmacyRepo struct {
+	Conn *pgx.Conn
+	// Timeout, if set, bounds every call unless the caller's context has an earlier deadline.
+	Timeout time.Duration
+
+	// tx is set in the repo passed to a WithTx func.
+	tx pgx.Tx
+}
+
+var _ pharmacy.Repo = PSQLPharmacyRepo{}
+
+// Open connects to postgres. A positive statementTimeout is also set as the session
+// statement_timeout, so the server aborts a slow query even if the client goes away.
+func Open(ctx context.Context, url string, statementTimeout time.Duration) (*pgx.Conn, error) {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	if statementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Ping(ctx)
+	if err != nil {
+		_ = conn.Close(ctx)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (r PSQLPharmacyRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
+
+func (r PSQLPharmacyRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	p, err := pgsql.ScanPharmacy(r.conn().QueryRow(ctx, pgsql.FindByCode, code))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return p, nil
+}
+
+// FindByCodes queues a FindByCode for every code in a pgx.Batch, so they are sent in one
+// round trip. A code that is not found does not fail the others.
+func (r PSQLPharmacyRepo) FindByCodes(ctx context.Context, codes []string) (map[string]*pharmacy.Pharmacy, []string, error) {
+	codes = pharmacy.UniqueCodes(codes)
+	if len(codes) == 0 {
+		return map[string]*pharmacy.Pharmacy{}, []string{}, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	for _, code := range codes {
+		batch.Queue(pgsql.FindByCode, code)
+	}
+	results := r.conn().SendBatch(ctx, batch)
+	defer results.Close()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0, len(codes))
+	for range codes {
+		p, err := pgsql.ScanPharmacy(results.QueryRow())
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, translateError(err)
+		}
+		pharmacies = append(pharmacies, p)
+	}
+	if err := results.Close(); err != nil {
+		return nil, nil, translateError(err)
+	}
+
+	found, missing := pharmacy.MatchCodes(codes, pharmacies)
+	return found, missing, nil
+}
+
+func (r PSQLPharmacyRepo) FindByPostcode(ctx context.Context, partial string) ([]*pharmacy.Pharmacy, error) {
+	query, args, err := pgsql.FindByPostcode(partial)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.query(ctx, query, args...)
+}
+
+// FindByPostcodePage is a paged FindByPostcode. Pass an empty cursor for the first page and
+// Page.Next for the following ones; an empty partial pages through every pharmacy.
+// Pages are read with a keyset on (postcode, code) so deep pages cost the same as the first.
+func (r PSQLPharmacyRepo) FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (*pharmacy.Page, error) {
+	size = pharmacy.PageSize(size)
+	query, args, err := pgsql.FindByPostcodePage(partial, cursor, size)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pharmacies, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgsql.NewPage(pharmacies, size), nil
+}
+
+// FindNearest returns at most limit pharmacies within radiusMeters of origin, nearest first.
+func (r PSQLPharmacyRepo) FindNearest(ctx context.Context, origin pharmacy.LatLng, radiusMeters float64, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	args, err := pgsql.FindNearestArgs(origin, radiusMeters, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().Query(ctx, pgsql.FindNearest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.NearbyPharmacy, 0)
+	for rows.Next() {
+		var distance float64
+		p, err := pgsql.ScanPharmacy(rows, &distance)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.NearbyPharmacy{Pharmacy: *p, Distance: distance})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// SearchByName returns at most limit pharmacies whose name or address matches query,
+// best match first, see pgsql.SearchByName.
+func (r PSQLPharmacyRepo) SearchByName(ctx context.Context, query string, limit int) ([]*pharmacy.ScoredPharmacy, error) {
+	args, err := pgsql.SearchByNameArgs(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().Query(ctx, pgsql.SearchByName, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.ScoredPharmacy, 0)
+	for rows.Next() {
+		var score float64
+		p, err := pgsql.ScanPharmacy(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.ScoredPharmacy{Pharmacy: *p, Score: score})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+func (r PSQLPharmacyRepo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.conn().Exec(ctx, pgsql.Insert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+func (r PSQLPharmacyRepo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		tag, err := r.conn().Exec(ctx, pgsql.Update, pgsql.Args(p)...)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(tag)
+	})
+}
+
+func (r PSQLPharmacyRepo) Delete(ctx context.Context, code string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		tag, err := r.conn().Exec(ctx, pgsql.Delete, code)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(tag)
+	})
+}
+
+// Upsert inserts p or, if a pharmacy with the same code already exists, overwrites it.
+// Unlike Insert it is safe to call repeatedly with the same data.
+func (r PSQLPharmacyRepo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.conn().Exec(ctx, pgsql.Upsert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+// History returns the changes recorded by the pharmacy_history trigger, oldest first.
+func (r PSQLPharmacyRepo) History(ctx context.Context, code string) ([]*pharmacy.Change, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().Query(ctx, pgsql.History, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*pharmacy.Change, 0)
+	for rows.Next() {
+		c, err := pgsql.ScanChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+func (r PSQLPharmacyRepo) FindByCodeAsOf(ctx context.Context, code string, at time.Time) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var after []byte
+	err := r.conn().QueryRow(ctx, pgsql.FindByCodeAsOf, code, pgsql.AsOfArg(at)).Scan(&after)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return pgsql.AsOf(after)
+}
+
+func (r PSQLPharmacyRepo) query(ctx context.Context, query string, args ...interface{}) ([]*pharmacy.Pharmacy, error) {
+	rows, err := r.conn().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0)
+	for rows.Next() {
+		p, err := pgsql.ScanPharmacy(rows)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, p)
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// checkAffected returns pharmacy.ErrNotFound if an update or delete did not match any row.
+func checkAffected(tag pgconn.CommandTag) error {
+	if tag.RowsAffected() == 0 {
+		return pharmacy.ErrNotFound
+	}
+	return nil
+}
+
+// translateError maps driver errors onto the pharmacy errors every backend returns.
+func translateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pharmacy.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgsql.TranslateError(err, pgErr.Code, pgErr.ColumnName, pgErr.Message, pgErr.Detail)
+	}
+	return err
+}
+
+// Transient reports if err, returned by the repo or Open, is likely to succeed if retried,
+// for retry.Policy.Do and retry.NewRepo.
+func Transient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgsql.Transient(err, pgErr.Code)
+	}
+	return pgsql.Transient(err, "")
+}
+
+// Conflict reports if err is a serialization failure or deadlock, the transient errors
+// that a retry on the same connection can get past. Unlike Transient it does not match a
+// lost connection, which a Conn is never reopened after.
+func Conflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == pgsql.SerializationFailure || pgErr.Code == pgsql.DeadlockDetected)
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is implemented by both *pgx.Conn and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// conn returns the transaction of a repo passed to a WithTx func, otherwise the Conn.
+func (r PSQLPharmacyRepo) conn() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.Conn
+}
+
+// WithTx calls fn with a repo whose calls all run in one transaction with opts, so
+// several inserts are atomic or a read sees the same data as a following write. The
+// transaction is committed if fn returns nil and rolled back if it returns an error or
+// panics.
+//
+// Calling WithTx on the repo passed to fn runs the nested fn in a savepoint, using pgx
+// pseudo nested transactions, so only its changes are rolled back if it fails, and the
+// outer fn can carry on. opts only apply to the outermost call. The repo passed to fn
+// must not be used after fn returns.
+func (r PSQLPharmacyRepo) WithTx(ctx context.Context, opts sql.TxOptions, fn func(tx PSQLPharmacyRepo) error) error {
+	// errors from fn are returned as they are, only those of pgx are translated
+	var fnFailed bool
+	call := func(tx pgx.Tx) error {
+		inTx := r
+		inTx.tx = tx
+		err := fn(inTx)
+		fnFailed = err != nil
+		return err
+	}
+
+	var err error
+	if r.tx != nil {
+		err = pgx.BeginFunc(ctx, r.tx, call)
+	} else {
+		var txOpts pgx.TxOptions
+		if txOpts, err = txOptions(opts); err != nil {
+			return err
+		}
+		err = pgx.BeginTxFunc(ctx, r.Conn, txOpts, call)
+	}
+	if fnFailed {
+		return err
+	}
+	return translateError(err)
+}
+
+// txOptions maps database/sql transaction options onto pgx ones.
+func txOptions(opts sql.TxOptions) (pgx.TxOptions, error) {
+	var txOpts pgx.TxOptions
+	switch opts.Isolation {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		txOpts.IsoLevel = pgx.ReadUncommitted
+	case sql.LevelReadCommitted:
+		txOpts.IsoLevel = pgx.ReadCommitted
+	case sql.LevelRepeatableRead:
+		txOpts.IsoLevel = pgx.RepeatableRead
+	case sql.LevelSerializable:
+		txOpts.IsoLevel = pgx.Serializable
+	default:
+		return txOpts, fmt.Errorf("isolation level %s is not supported by postgres", opts.Isolation)
+	}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+	return txOpts, nil
+}
+
+// audited calls write in a transaction that first sets the actor in ctx, see
+// pharmacy.WithActor, for the pharmacy_history trigger. Without an actor write is called
+// on r as it is, and the trigger records the database user.
+func (r PSQLPharmacyRepo) audited(ctx context.Context, write func(r PSQLPharmacyRepo) error) error {
+	actor, ok := pharmacy.ActorFrom(ctx)
+	if !ok {
+		return write(r)
+	}
+	if r.tx == nil {
+		return r.WithTx(ctx, sql.TxOptions{}, func(tx PSQLPharmacyRepo) error {
+			return tx.audited(ctx, write)
+		})
+	}
+	if _, err := r.tx.Exec(ctx, pgsql.SetActor, actor); err != nil {
+		return translateError(err)
+	}
+	return write(r)
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/ayubmalik/go-cookbook/pharmacy/retry"
+	"github.com/jackc/pgx/v5"
+)
+
+// reconnect is the backoff of a watch that can not connect, which retries until its
+// context is done. It starts again from InitialInterval for every connection lost.
+var reconnect = retry.Policy{
+	InitialInterval: retry.Default.InitialInterval,
+	MaxInterval:     retry.Default.MaxInterval,
+	Multiplier:      retry.Default.Multiplier,
+}
+
+// Watch sends the pharmacies written by anyone, as notified by the trigger of the
+// 0005_pharmacy_notify migration, until ctx is done and the channel is closed. It listens
+// on a connection of its own, opened with the config of r.Conn as r.Conn can not wait for
+// notifications while running queries. If the connection is lost Watch reconnects, with
+// backoff if it can not, and listens again, sending a pharmacy.OpResync event each time
+// it listens.
+func (r PSQLPharmacyRepo) Watch(ctx context.Context) <-chan pharmacy.ChangeEvent {
+	events := make(chan pharmacy.ChangeEvent)
+	config := r.Conn.Config()
+	policy := reconnect
+	go func() {
+		defer close(events)
+		for {
+			// only connecting is retried, so the backoff starts again for every session
+			var conn *pgx.Conn
+			err := policy.Do(ctx, func(error) bool { return true }, func() (err error) {
+				conn, err = listen(ctx, config)
+				return err
+			})
+			if err != nil {
+				return
+			}
+			_ = notify(ctx, conn, events)
+			_ = conn.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// listen opens a connection that listens for pharmacy changes.
+func listen(ctx context.Context, config *pgx.ConnConfig) (*pgx.Conn, error) {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, pgsql.Listen); err != nil {
+		_ = conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
+// notify sends a pharmacy.OpResync event then the notifications of conn to events until
+// the connection is lost or ctx is done.
+func notify(ctx context.Context, conn *pgx.Conn, events chan<- pharmacy.ChangeEvent) error {
+	if err := send(ctx, events, pharmacy.ChangeEvent{Operation: pharmacy.OpResync}); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		// the trigger only sends valid payloads, so skip any sent by hand
+		e, err := pgsql.ParseChangeEvent(n.Payload)
+		if err != nil {
+			continue
+		}
+		if err := send(ctx, events, e); err != nil {
+			return err
+		}
+	}
+}
+
+func send(ctx context.Context, events chan<- pharmacy.ChangeEvent, e pharmacy.ChangeEvent) error {
+	select {
+	case events <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -10,3 +10,7 @@ create table if not exists pharmacy (
   lat decimal,
   lng decimal
 );
+
+create index if not exists pharmacy_lat_lng_idx on pharmacy (lat, lng);
+
+create index if not exists pharmacy_postcode_code_idx on pharmacy (postcode, code);
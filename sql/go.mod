@@ -1,5 +1,19 @@
 module github.com/ayubmalik/go-cookbook/sql
 
-go 1.16
+go 1.22
 
-require github.com/lib/pq v1.10.2
+require (
+	github.com/ayubmalik/go-cookbook/config v0.0.0-00010101000000-000000000000
+	github.com/ayubmalik/go-cookbook/pharmacy v0.0.0-00010101000000-000000000000
+	github.com/lib/pq v1.10.2
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.3 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/ayubmalik/go-cookbook/pharmacy => ../pharmacy
+
+replace github.com/ayubmalik/go-cookbook/config => ../config
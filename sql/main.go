@@ -1,117 +1,92 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-
-	_ "github.com/lib/pq"
+	"os/signal"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/config"
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/appconfig"
+	"github.com/ayubmalik/go-cookbook/pharmacy/auth"
+	"github.com/ayubmalik/go-cookbook/pharmacy/cache"
+	"github.com/ayubmalik/go-cookbook/pharmacy/httpapi"
+	"github.com/ayubmalik/go-cookbook/pharmacy/metrics"
+	"github.com/ayubmalik/go-cookbook/pharmacy/migrations"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/ayubmalik/go-cookbook/pharmacy/retry"
+	"github.com/ayubmalik/go-cookbook/sql/postgres"
 )
 
-type Pharmacy struct {
-	Code      string
-	Name      string
-	AddrLine1 string
-	AddrLine2 string
-	AddrLine3 string
-	AddrLine4 string
-	Postcode  string
-	LatLng    LatLng
-}
-
-type LatLng struct {
-	Lat float32
-	Lng float32
-}
-
-type PharmacyRepo interface {
-	FindByCode(code string) (*Pharmacy, error)
-	FindByPostcode(partial string) ([]*Pharmacy, error)
-	Insert(p Pharmacy) error
+// appConfig adds the pool settings to the config shared by the sql examples.
+type appConfig struct {
+	appconfig.Config
+	Pool pgsql.PoolSettings `config:"pool"`
 }
 
-type PSQLPharmacyRepo struct {
-	DB *sql.DB
-	PharmacyRepo
+func (c appConfig) Validate() error {
+	return errors.Join(c.Config.Validate(), c.Pool.ValidateSQL())
 }
 
-func (r PSQLPharmacyRepo) FindByCode(code string) (*Pharmacy, error) {
-	sql := `select code, name, addr_line_1, addr_line_2, addr_line_3, addr_line_4, postcode
-		from pharmacy where code = $1`
-
-	p := &Pharmacy{}
-	row := r.DB.QueryRow(sql, code)
-	err := row.Scan(&p.Code, &p.Name, &p.AddrLine1, &p.AddrLine2, &p.AddrLine3, &p.AddrLine4, &p.Postcode)
-	if err != nil {
-		return nil, err
+func main() {
+	cfg := appConfig{Config: appconfig.DefaultConfig()}
+	args, err := config.Load(&cfg, "sql", os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-
-	return p, nil
-}
-
-func (r PSQLPharmacyRepo) FindByPostcode(partial string) ([]*Pharmacy, error) {
-	sql := `select code, name, addr_line_1, addr_line_2, addr_line_3, addr_line_4, postcode
-		from pharmacy where postcode like concat($1::text, '%')`
-
-	rows, err := r.DB.Query(sql, partial)
 	if err != nil {
-		return nil, err
+		log.Fatalln(err)
 	}
-	defer rows.Close()
-
-	pharmacies := make([]*Pharmacy, 0)
-	for rows.Next() {
-		p := &Pharmacy{}
-		err := rows.Scan(&p.Code, &p.Name, &p.AddrLine1, &p.AddrLine2, &p.AddrLine3, &p.AddrLine4, &p.Postcode)
-
-		if err != nil {
-			return nil, err
+	if len(args) > 0 && args[0] == "config" {
+		if err := config.Print(os.Stdout, &cfg); err != nil {
+			log.Fatalln(err)
 		}
-		pharmacies = append(pharmacies, p)
+		return
 	}
 
-	return pharmacies, nil
-}
-
-func (r PSQLPharmacyRepo) Insert(p Pharmacy) error {
-	sql := `insert into pharmacy(code, name, addr_line_1, addr_line_2, addr_line_3, addr_line_4,
-		postcode, lat, lng) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-
-	_, err := r.DB.Exec(
-		sql,
-		p.Code,
-		p.Name,
-		p.AddrLine1,
-		p.AddrLine2,
-		p.AddrLine3,
-		p.AddrLine4,
-		p.Postcode,
-		p.LatLng.Lat,
-		p.LatLng.Lng,
-	)
-
-	if err != nil {
-		return err
+	policy := retry.Default
+	policy.MaxElapsedTime = cfg.RetryMaxElapsed
+	policy.OnRetry = func(err error, wait time.Duration) {
+		log.Printf("retrying in %s after %v\n", wait.Round(time.Millisecond), err)
 	}
 
-	return nil
-}
+	// cancel any in flight query, or retries, on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-func main() {
-	db, err := openDB()
+	// postgres may still be starting, e.g. just after docker compose up
+	var db *sql.DB
+	err = policy.Do(ctx, postgres.Transient, func() (err error) {
+		db, err = postgres.Open(cfg.DB.DSN(), cfg.Timeout, cfg.Pool)
+		return err
+	})
 	if err != nil {
 		log.Fatalf("could not get DB connection %s\n", err)
 	}
 	defer db.Close()
 
-	repo := PSQLPharmacyRepo{
-		DB: db,
+	repo := cache.NewRepo(retry.NewRepo(postgres.PSQLPharmacyRepo{
+		DB:      db,
+		Timeout: cfg.Timeout,
+	}, policy, postgres.Transient), cfg.Cache)
+
+	if len(args) > 0 {
+		if err := run(ctx, db, repo, args, os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
 	}
 
 	// find single
 	code := "FA512"
-	p, err := repo.FindByCode(code)
+	p, err := repo.FindByCode(ctx, code)
 	if err != nil {
 		log.Fatalf("could not find pharmacy with code %s %v", code, err)
 	}
@@ -119,7 +94,7 @@ func main() {
 
 	// find multiple
 	postcode := "LE"
-	pharmacies, err := repo.FindByPostcode("LE")
+	pharmacies, err := repo.FindByPostcode(ctx, "LE")
 	if err != nil {
 		log.Fatalf("could not find pharmacies with postcode %s %v", postcode, err)
 	}
@@ -127,50 +102,131 @@ func main() {
 	log.Println(pharmacies[0].Code, pharmacies[0].Name, pharmacies[0].Postcode)
 	log.Println(pharmacies[1].Code, pharmacies[1].Name, pharmacies[1].Postcode)
 
-	pharmacy := Pharmacy{
+	// walk every page of a postcode search
+	cursor := ""
+	for {
+		page, err := repo.FindByPostcodePage(ctx, postcode, cursor, 10)
+		if err != nil {
+			log.Fatalf("could not page pharmacies with postcode %s %v", postcode, err)
+		}
+		for _, p := range page.Pharmacies {
+			log.Println(p.Code, p.Name, p.Postcode)
+		}
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+
+	// find nearest
+	origin := pharmacy.LatLng{Lat: 52.670024, Lng: -0.730285}
+	nearest, err := repo.FindNearest(ctx, origin, 50000, 5)
+	if err != nil {
+		log.Fatalf("could not find pharmacies near %v %v", origin, err)
+	}
+	for _, n := range nearest {
+		log.Printf("%s %s %.0fm\n", n.Code, n.Name, n.Distance)
+	}
+
+	// search by name, allowing for typos
+	found, err := repo.SearchByName(ctx, "rutland okham", 5)
+	if err != nil {
+		log.Fatalf("could not search pharmacies by name %v", err)
+	}
+	for _, f := range found {
+		log.Printf("%s %s %.2f\n", f.Code, f.Name, f.Score)
+	}
+
+	// the late night pharmacy is open from 08:00 until 00:30 the next morning, every day
+	var hours pharmacy.OpeningHours
+	for day := range hours.Weekly {
+		hours.Weekly[day] = []pharmacy.Session{{Opens: pharmacy.Clock(8, 0), Closes: pharmacy.Clock(0, 30)}}
+	}
+	if err := repo.SetOpeningHours(ctx, "FC826", hours); err != nil {
+		log.Fatalf("could not set opening hours %v", err)
+	}
+	open, err := repo.FindOpenNear(ctx, origin, time.Now(), 5)
+	if err != nil {
+		log.Fatalf("could not find open pharmacies near %v %v", origin, err)
+	}
+	for _, n := range open {
+		log.Printf("open now %s %s %.0fm\n", n.Code, n.Name, n.Distance)
+	}
+
+	newPharmacy := pharmacy.Pharmacy{
 		Code:      "NEW1",
 		Name:      "A test pharmacy",
-		AddrLine1: "line1",
-		AddrLine2: "line2",
-		AddrLine3: "line3",
-		AddrLine4: "line4",
-		Postcode:  "postccode",
-		LatLng:    LatLng{Lat: 1.1, Lng: 2.2},
+		AddrLine1: sql.NullString{String: "line1", Valid: true},
+		AddrLine2: sql.NullString{String: "line2", Valid: true},
+		AddrLine3: sql.NullString{String: "line3", Valid: true},
+		AddrLine4: sql.NullString{}, // NULL
+		Postcode:  "LE1 5AB",
+		Phone:     sql.NullString{String: "01234 567890", Valid: true},
+		LatLng:    &pharmacy.LatLng{Lat: 1.1, Lng: 2.2},
+	}
+
+	// upsert so that a second run does not fail with a PK violation
+	err = repo.Upsert(ctx, newPharmacy)
+	if err != nil {
+		log.Fatalf("could not upsert pharmacy %v", err)
+	}
+
+	newPharmacy.Name = "An updated test pharmacy"
+	err = repo.Update(ctx, newPharmacy)
+	if err != nil {
+		log.Fatalf("could not update pharmacy %v", err)
 	}
 
-	// will error on second run due to PK violation :)
-	err = repo.Insert(pharmacy)
+	err = repo.Delete(ctx, newPharmacy.Code)
 	if err != nil {
-		log.Fatalf("could not insert pharmacy %v", err)
+		log.Fatalf("could not delete pharmacy %v", err)
 	}
 
 }
 
-func openDB() (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		getEnvOrDefault("DB_HOST", "localhost"),
-		getEnvOrDefault("DB_PORT", "5432"),
-		getEnvOrDefault("DB_USER", "root"),
-		getEnvOrDefault("DB_PASSWORD", "password"),
-		getEnvOrDefault("DB_NAME", "pharmacy"),
-	)
+// run executes the command named by args[0] instead of the lookup examples in main.
+func run(ctx context.Context, db *sql.DB, repo *cache.Repo, args []string, stdout io.Writer) error {
+	switch args[0] {
+	case "serve":
+		stats := metrics.Handler(func() []metrics.Metric {
+			return append(metrics.DBStats(db.Stats()), repo.Metrics()...)
+		})
+		return httpapi.Run(ctx, httpapi.WithMetrics(httpapi.NewHandler(repo), stats), args[1:], stdout)
+	case "token":
+		return auth.Run(args[1:], stdout)
+	case "import":
+		return runImport(ctx, db, args[1:], stdout)
+	case "migrate":
+		return migrations.Run(ctx, db, args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
 
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
+// runImport loads a pharmacy CSV file into the pharmacy table, printing the lines rejected
+// for failing to parse or a duplicate code, see postgres.Import.
+func runImport(ctx context.Context, db *sql.DB, args []string, stdout io.Writer) error {
+	var (
+		flags     = flag.NewFlagSet("import", flag.ExitOnError)
+		batchSize = flags.Int("batch", 500, "the number of rows per insert statement")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [-batch n] file.csv")
 	}
 
-	err = db.Ping()
+	f, err := os.Open(flags.Arg(0))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return db, nil
-}
+	defer f.Close()
 
-func getEnvOrDefault(key string, defValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	stats, err := postgres.Import(ctx, db, f, *batchSize)
+	if err != nil {
+		return err
 	}
-	return defValue
+	stats.Print(stdout)
+	return nil
 }
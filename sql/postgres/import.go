@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+// MaxBatchSize is the largest batch Import accepts, postgres allows at most 65535
+// parameters per statement.
+var MaxBatchSize = 65535 / len(pharmacy.CSVColumns)
+
+// Import inserts the valid rows of a pharmacy CSV with multi row inserts of up to
+// batchSize rows. database/sql has no COPY support so this is the closest equivalent. A
+// row whose code is already in the table, or earlier in the file, is rejected by line like
+// a row that fails to parse. All batches run in one transaction, so any other database
+// error fails the whole import and leaves the table unchanged.
+func Import(ctx context.Context, db *sql.DB, r io.Reader, batchSize int) (*pharmacy.ImportStats, error) {
+	if batchSize < 1 || batchSize > MaxBatchSize {
+		return nil, fmt.Errorf("batch size must be between 1 and %d", MaxBatchSize)
+	}
+
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stats := &pharmacy.ImportStats{}
+	batch := make([]*pharmacy.Pharmacy, 0, batchSize)
+	lines := make([]int, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, err := insertBatch(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		for i, p := range batch {
+			// a code is returned once, for the first of its rows in the batch
+			if inserted[p.Code] {
+				delete(inserted, p.Code)
+				stats.Imported++
+				continue
+			}
+			stats.Rejected = append(stats.Rejected, pharmacy.Rejection{
+				Line:   lines[i],
+				Reason: fmt.Sprintf("duplicate code %q", p.Code),
+			})
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	pr := pharmacy.NewCSVReader(r)
+	for {
+		p, err := pr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		batch = append(batch, p)
+		lines = append(lines, pr.Line())
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	stats.Rejected = append(stats.Rejected, pr.Rejected...)
+	sort.Slice(stats.Rejected, func(i, j int) bool {
+		return stats.Rejected[i].Line < stats.Rejected[j].Line
+	})
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
+
+// insertBatch inserts batch, skipping the rows with a code already taken, and returns the
+// codes inserted.
+func insertBatch(ctx context.Context, tx *sql.Tx, batch []*pharmacy.Pharmacy) (map[string]bool, error) {
+	query, args := batchInsert(batch)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(batch))
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		inserted[code] = true
+	}
+	return inserted, translateError(rows.Err())
+}
+
+// batchInsert builds a single insert statement and its arguments for every pharmacy in
+// batch, returning the codes of the rows inserted.
+func batchInsert(batch []*pharmacy.Pharmacy) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString("insert into pharmacy(" + strings.Join(pharmacy.CSVColumns, ", ") + ") values ")
+
+	args := make([]interface{}, 0, len(batch)*len(pharmacy.CSVColumns))
+	for i, p := range batch {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range pharmacy.CSVColumns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", len(args)+j+1)
+		}
+		b.WriteString(")")
+
+		args = append(args, pgsql.Args(*p)...)
+	}
+	b.WriteString(" on conflict (code) do nothing returning rtrim(code)")
+	return b.String(), args
+}
@@ -0,0 +1,331 @@
+// Package postgres implements pharmacy.Repo with database/sql and the lib/pq driver.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+	"github.com/lib/pq"
+)
+
+type PSQLPharmacyRepo struct {
+	DB *sql.DB
+	// Timeout, if set, bounds every call unless the caller's context has an earlier deadline.
+	Timeout time.Duration
+
+	// tx is set in the repo passed to a WithTx func, nested depth savepoints deep.
+	tx    *sql.Tx
+	depth int
+}
+
+var _ pharmacy.Repo = PSQLPharmacyRepo{}
+
+// Open connects to postgres with a key=value dsn and a pool tuned by settings. A positive statementTimeout is also
+// set as the session statement_timeout, so the server aborts a slow query even if the client goes away.
+func Open(dsn string, statementTimeout time.Duration, settings pgsql.PoolSettings) (*sql.DB, error) {
+	if statementTimeout > 0 {
+		// unknown keys are passed on by lib/pq as run-time parameters
+		dsn += fmt.Sprintf(" statement_timeout=%d", statementTimeout.Milliseconds())
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.Configure(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (r PSQLPharmacyRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
+
+func (r PSQLPharmacyRepo) FindByCode(ctx context.Context, code string) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	p, err := pgsql.ScanPharmacy(r.conn().QueryRowContext(ctx, pgsql.FindByCode, code))
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return p, nil
+}
+
+// FindByCodes selects every code with one `= any($1)` statement.
+func (r PSQLPharmacyRepo) FindByCodes(ctx context.Context, codes []string) (map[string]*pharmacy.Pharmacy, []string, error) {
+	codes = pharmacy.UniqueCodes(codes)
+	if len(codes) == 0 {
+		return map[string]*pharmacy.Pharmacy{}, []string{}, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pharmacies, err := r.query(ctx, pgsql.FindByCodes, pq.Array(codes))
+	if err != nil {
+		return nil, nil, err
+	}
+	found, missing := pharmacy.MatchCodes(codes, pharmacies)
+	return found, missing, nil
+}
+
+func (r PSQLPharmacyRepo) FindByPostcode(ctx context.Context, partial string) ([]*pharmacy.Pharmacy, error) {
+	query, args, err := pgsql.FindByPostcode(partial)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.query(ctx, query, args...)
+}
+
+// FindByPostcodePage is a paged FindByPostcode. Pass an empty cursor for the first page and
+// Page.Next for the following ones; an empty partial pages through every pharmacy.
+// Pages are read with a keyset on (postcode, code) so deep pages cost the same as the first.
+func (r PSQLPharmacyRepo) FindByPostcodePage(ctx context.Context, partial string, cursor string, size int) (*pharmacy.Page, error) {
+	size = pharmacy.PageSize(size)
+	query, args, err := pgsql.FindByPostcodePage(partial, cursor, size)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	pharmacies, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgsql.NewPage(pharmacies, size), nil
+}
+
+// FindNearest returns at most limit pharmacies within radiusMeters of origin, nearest first.
+func (r PSQLPharmacyRepo) FindNearest(ctx context.Context, origin pharmacy.LatLng, radiusMeters float64, limit int) ([]*pharmacy.NearbyPharmacy, error) {
+	args, err := pgsql.FindNearestArgs(origin, radiusMeters, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().QueryContext(ctx, pgsql.FindNearest, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.NearbyPharmacy, 0)
+	for rows.Next() {
+		var distance float64
+		p, err := pgsql.ScanPharmacy(rows, &distance)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.NearbyPharmacy{Pharmacy: *p, Distance: distance})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// SearchByName returns at most limit pharmacies whose name or address matches query,
+// best match first, see pgsql.SearchByName.
+func (r PSQLPharmacyRepo) SearchByName(ctx context.Context, query string, limit int) ([]*pharmacy.ScoredPharmacy, error) {
+	args, err := pgsql.SearchByNameArgs(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().QueryContext(ctx, pgsql.SearchByName, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.ScoredPharmacy, 0)
+	for rows.Next() {
+		var score float64
+		p, err := pgsql.ScanPharmacy(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, &pharmacy.ScoredPharmacy{Pharmacy: *p, Score: score})
+	}
+
+	return pharmacies, rows.Err()
+}
+
+func (r PSQLPharmacyRepo) Insert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.conn().ExecContext(ctx, pgsql.Insert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+func (r PSQLPharmacyRepo) Update(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		res, err := r.conn().ExecContext(ctx, pgsql.Update, pgsql.Args(p)...)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(res)
+	})
+}
+
+func (r PSQLPharmacyRepo) Delete(ctx context.Context, code string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		res, err := r.conn().ExecContext(ctx, pgsql.Delete, code)
+		if err != nil {
+			return translateError(err)
+		}
+		return checkAffected(res)
+	})
+}
+
+// Upsert inserts p or, if a pharmacy with the same code already exists, overwrites it.
+// Unlike Insert it is safe to call repeatedly with the same data.
+func (r PSQLPharmacyRepo) Upsert(ctx context.Context, p pharmacy.Pharmacy) error {
+	p, err := pharmacy.Normalize(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.audited(ctx, func(r PSQLPharmacyRepo) error {
+		_, err := r.conn().ExecContext(ctx, pgsql.Upsert, pgsql.Args(p)...)
+		return translateError(err)
+	})
+}
+
+// History returns the changes recorded by the pharmacy_history trigger, oldest first.
+func (r PSQLPharmacyRepo) History(ctx context.Context, code string) ([]*pharmacy.Change, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.conn().QueryContext(ctx, pgsql.History, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*pharmacy.Change, 0)
+	for rows.Next() {
+		c, err := pgsql.ScanChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+func (r PSQLPharmacyRepo) FindByCodeAsOf(ctx context.Context, code string, at time.Time) (*pharmacy.Pharmacy, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var after []byte
+	err := r.conn().QueryRowContext(ctx, pgsql.FindByCodeAsOf, code, pgsql.AsOfArg(at)).Scan(&after)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return pgsql.AsOf(after)
+}
+
+func (r PSQLPharmacyRepo) query(ctx context.Context, query string, args ...interface{}) ([]*pharmacy.Pharmacy, error) {
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pharmacies := make([]*pharmacy.Pharmacy, 0)
+	for rows.Next() {
+		p, err := pgsql.ScanPharmacy(rows)
+		if err != nil {
+			return nil, err
+		}
+		pharmacies = append(pharmacies, p)
+	}
+
+	return pharmacies, rows.Err()
+}
+
+// checkAffected returns pharmacy.ErrNotFound if an update or delete did not match any row.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pharmacy.ErrNotFound
+	}
+	return nil
+}
+
+// translateError maps driver errors onto the pharmacy errors every backend returns.
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pharmacy.ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pgsql.TranslateError(err, string(pqErr.Code), pqErr.Column, pqErr.Message, pqErr.Detail)
+	}
+	return err
+}
+
+// Transient reports if err, returned by the repo or Open, is likely to succeed if retried,
+// for retry.Policy.Do and retry.NewRepo.
+func Transient(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pgsql.Transient(err, string(pqErr.Code))
+	}
+	return pgsql.Transient(err, "")
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ayubmalik/go-cookbook/pharmacy"
+	"github.com/ayubmalik/go-cookbook/pharmacy/pgsql"
+)
+
+// execQuerier is implemented by both *sql.DB and *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the transaction of a repo passed to a WithTx func, otherwise the DB.
+func (r PSQLPharmacyRepo) conn() execQuerier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.DB
+}
+
+// WithTx calls fn with a repo whose calls all run in one transaction with opts, so
+// several inserts are atomic or a read sees the same data as a following write. The
+// transaction is committed if fn returns nil and rolled back if it returns an error or
+// panics.
+//
+// Calling WithTx on the repo passed to fn runs the nested fn in a savepoint, so only its
+// changes are rolled back if it fails, and the outer fn can carry on. opts only apply to
+// the outermost call. The repo passed to fn must not be used concurrently or after fn
+// returns.
+func (r PSQLPharmacyRepo) WithTx(ctx context.Context, opts sql.TxOptions, fn func(tx PSQLPharmacyRepo) error) error {
+	if r.tx != nil {
+		return r.withSavepoint(ctx, fn)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, &opts)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	inTx := r
+	inTx.tx = tx
+	if err := fn(inTx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return translateError(tx.Commit())
+}
+
+// withSavepoint calls fn within a savepoint of the transaction of r.
+func (r PSQLPharmacyRepo) withSavepoint(ctx context.Context, fn func(tx PSQLPharmacyRepo) error) error {
+	name := fmt.Sprintf("pharmacy_%d", r.depth+1)
+	if _, err := r.tx.ExecContext(ctx, "savepoint "+name); err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = r.tx.ExecContext(ctx, "rollback to savepoint "+name)
+			panic(p)
+		}
+	}()
+
+	nested := r
+	nested.depth++
+	if err := fn(nested); err != nil {
+		// ignore a rollback error, as the transaction is already failing
+		_, _ = r.tx.ExecContext(ctx, "rollback to savepoint "+name)
+		return err
+	}
+	_, err := r.tx.ExecContext(ctx, "release savepoint "+name)
+	return translateError(err)
+}
+
+// audited calls write in a transaction that first sets the actor in ctx, see
+// pharmacy.WithActor, for the pharmacy_history trigger. Without an actor write is called
+// on r as it is, and the trigger records the database user.
+func (r PSQLPharmacyRepo) audited(ctx context.Context, write func(r PSQLPharmacyRepo) error) error {
+	actor, ok := pharmacy.ActorFrom(ctx)
+	if !ok {
+		return write(r)
+	}
+	if r.tx == nil {
+		return r.WithTx(ctx, sql.TxOptions{}, func(tx PSQLPharmacyRepo) error {
+			return tx.audited(ctx, write)
+		})
+	}
+	if _, err := r.tx.ExecContext(ctx, pgsql.SetActor, actor); err != nil {
+		return translateError(err)
+	}
+	return write(r)
+}